@@ -0,0 +1,46 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"database/sql"
+)
+
+// queryIDColumn is the column of the row returned by the query with wait=false.
+const queryIDColumn = "query_id"
+
+// QueryerContext is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type QueryerContext interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// StartQuery starts the query without waiting for the results, and returns the query ID.
+// The args are the same as db.QueryContext.
+// The results can be fetched later, even by another process, with AttachQuery.
+func StartQuery(ctx context.Context, db QueryerContext, query string, args ...any) (string, error) {
+	rows, err := db.QueryContext(ctx, query, append(append([]any(nil), args...), sql.Named("wait", false))...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var queryID string
+	if rows.Next() {
+		if err := rows.Scan(&queryID); err != nil {
+			return "", err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if queryID == "" {
+		return "", sql.ErrNoRows
+	}
+	return queryID, nil
+}
+
+// AttachQuery waits for the results of the query started before, without calling StartQuery.
+// It is the same as db.QueryContext(ctx, "", sql.Named("query_id", queryID), args...).
+// args are the named parameters timeout, priority, the scan budget and stop_on_abort.
+// The query keeps running when the waiting is aborted, unless sql.Named("stop_on_abort", true) is given.
+func AttachQuery(ctx context.Context, db QueryerContext, queryID string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(ctx, "", append([]any{sql.Named("query_id", queryID)}, args...)...)
+}
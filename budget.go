@@ -0,0 +1,99 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// ScanBudgetPolicy is the policy when the scan budget (max_bytes_scanned, max_records_scanned) is exceeded.
+type ScanBudgetPolicy string
+
+const (
+	// ScanBudgetPolicyError stops the query and returns ScanBudgetExceededError. This is the default.
+	ScanBudgetPolicyError ScanBudgetPolicy = "error"
+	// ScanBudgetPolicyPartial stops the query, logs the error and returns the partial results found so far.
+	ScanBudgetPolicyPartial ScanBudgetPolicy = "partial"
+)
+
+func parseScanBudgetPolicy(s string) (ScanBudgetPolicy, error) {
+	switch p := ScanBudgetPolicy(s); p {
+	case ScanBudgetPolicyError, ScanBudgetPolicyPartial:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown scan_budget_policy %q", s)
+}
+
+// ErrScanBudgetExceeded is matched by errors.Is for ScanBudgetExceededError.
+var ErrScanBudgetExceeded = errors.New("scan budget exceeded")
+
+// ScanBudgetExceededError is returned when the query is stopped because it scanned more than max_bytes_scanned or max_records_scanned.
+type ScanBudgetExceededError struct {
+	QueryID string
+	Budget  string // max_bytes_scanned or max_records_scanned
+	Max     float64
+	Scanned float64
+}
+
+func (e *ScanBudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: query %s scanned %.0f, %s=%.0f", ErrScanBudgetExceeded, e.QueryID, e.Scanned, e.Budget, e.Max)
+}
+
+func (e *ScanBudgetExceededError) Is(target error) bool {
+	return target == ErrScanBudgetExceeded
+}
+
+// scanBudget is shared by the Logs Insights queries executed by one QueryContext,
+// so that the budget applies to the total of split, partitioned and fan out queries.
+type scanBudget struct {
+	maxBytesScanned   float64
+	maxRecordsScanned float64
+	policy            ScanBudgetPolicy
+
+	mu      sync.Mutex
+	scanned map[string]types.QueryStatistics
+}
+
+type scanBudgetKey struct{}
+
+func withScanBudget(ctx context.Context, opts *queryOptions) context.Context {
+	if opts.maxBytesScanned <= 0 && opts.maxRecordsScanned <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, scanBudgetKey{}, &scanBudget{
+		maxBytesScanned:   float64(opts.maxBytesScanned),
+		maxRecordsScanned: float64(opts.maxRecordsScanned),
+		policy:            opts.scanBudgetPolicy,
+		scanned:           make(map[string]types.QueryStatistics),
+	})
+}
+
+func getScanBudget(ctx context.Context) *scanBudget {
+	b, _ := ctx.Value(scanBudgetKey{}).(*scanBudget)
+	return b
+}
+
+// check updates the statistics of the query and returns ScanBudgetExceededError if the total exceeds the budget.
+func (b *scanBudget) check(queryID string, stats *types.QueryStatistics) error {
+	if b == nil || stats == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scanned[queryID] = *stats
+	var bytesScanned, recordsScanned float64
+	for _, s := range b.scanned {
+		bytesScanned += s.BytesScanned
+		recordsScanned += s.RecordsScanned
+	}
+	if b.maxBytesScanned > 0 && bytesScanned > b.maxBytesScanned {
+		return &ScanBudgetExceededError{QueryID: queryID, Budget: "max_bytes_scanned", Max: b.maxBytesScanned, Scanned: bytesScanned}
+	}
+	if b.maxRecordsScanned > 0 && recordsScanned > b.maxRecordsScanned {
+		return &ScanBudgetExceededError{QueryID: queryID, Budget: "max_records_scanned", Max: b.maxRecordsScanned, Scanned: recordsScanned}
+	}
+	return nil
+}
@@ -2,9 +2,14 @@ package cloudwatchlogsinsightsdriver
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 // CloudwatchLogsClient is the interface for the Cloudwatch Logs Insights client.
@@ -12,12 +17,50 @@ type CloudwatchLogsClient interface {
 	StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
 	GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
 	StopQuery(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error)
+	DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error)
 }
 
+// ClientConstructor is the constructor for the Cloudwatch Logs Insights client.
+type ClientConstructor func(ctx context.Context, cfg *CloudwatchLogsInsightsConfig) (CloudwatchLogsClient, error)
+
 // 　CloudwatchLogsClientConstructor is the constructor for the Cloudwatch Logs Insights client.
+//
+// Deprecated: use RegisterClientConstructor and the client DSN parameter. It is used only when the client parameter is not set.
 var CloudwatchLogsClientConstructor func(ctx context.Context, cfg *CloudwatchLogsInsightsConfig) (CloudwatchLogsClient, error)
 
+var (
+	clientConstructorsMu sync.RWMutex
+	clientConstructors   = make(map[string]ClientConstructor)
+)
+
+// RegisterClientConstructor makes the client constructor available by the name, selected by the client DSN parameter.
+// For example, after RegisterClientConstructor("cached", fn), cloudwatch://?client=cached uses fn.
+// If RegisterClientConstructor is called twice with the same name or if fn is nil, it panics.
+func RegisterClientConstructor(name string, fn ClientConstructor) {
+	clientConstructorsMu.Lock()
+	defer clientConstructorsMu.Unlock()
+	if name == "" {
+		panic("cloudwatchlogsinsightsdriver: client constructor name is empty")
+	}
+	if fn == nil {
+		panic("cloudwatchlogsinsightsdriver: register client constructor is nil")
+	}
+	if _, dup := clientConstructors[name]; dup {
+		panic("cloudwatchlogsinsightsdriver: register called twice for client constructor " + name)
+	}
+	clientConstructors[name] = fn
+}
+
 func newCloudwatchLogsClientClient(ctx context.Context, cfg *CloudwatchLogsInsightsConfig) (CloudwatchLogsClient, error) {
+	if cfg.ClientName != "" {
+		clientConstructorsMu.RLock()
+		fn, ok := clientConstructors[cfg.ClientName]
+		clientConstructorsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("client constructor %q is not registered", cfg.ClientName)
+		}
+		return fn(ctx, cfg)
+	}
 	if CloudwatchLogsClientConstructor != nil {
 		return CloudwatchLogsClientConstructor(ctx, cfg)
 	}
@@ -25,15 +68,55 @@ func newCloudwatchLogsClientClient(ctx context.Context, cfg *CloudwatchLogsInsig
 }
 
 // DefaultCloudwatchLogsClientConstructor is the default constructor for the Cloudwatch Logs Insights client.
+// It honours the region, the profile, the role, the endpoint and the retry settings of the config.
 func DefaultCloudwatchLogsClientConstructor(ctx context.Context, cfg *CloudwatchLogsInsightsConfig) (CloudwatchLogsClient, error) {
 	optFns := []func(*config.LoadOptions) error{}
 	if cfg.Region != "" {
 		optFns = append(optFns, config.WithRegion(cfg.Region))
 	}
+	if cfg.Profile != "" {
+		optFns = append(optFns, config.WithSharedConfigProfile(cfg.Profile))
+	}
+	if cfg.RetryMaxAttempts != 0 {
+		optFns = append(optFns, config.WithRetryMaxAttempts(cfg.RetryMaxAttempts))
+	}
+	if cfg.RetryMode != "" {
+		optFns = append(optFns, config.WithRetryMode(cfg.RetryMode))
+	}
 	awsCfg, err := config.LoadDefaultConfig(ctx, optFns...)
 	if err != nil {
 		return nil, err
 	}
-	client := cloudwatchlogs.NewFromConfig(awsCfg, cfg.OptFns...)
-	return client, nil
+	return newCloudwatchLogsClientFromAWSConfig(awsCfg, cfg), nil
+}
+
+// newCloudwatchLogsClientFromAWSConfig constructs the client with the role and the endpoint of the config.
+// The endpoint is also used by the STS client that assumes the role, for example both are LocalStack.
+func newCloudwatchLogsClientFromAWSConfig(awsCfg aws.Config, cfg *CloudwatchLogsInsightsConfig) CloudwatchLogsClient {
+	if cfg.RoleARN != "" {
+		stsClient := sts.NewFromConfig(awsCfg, func(o *sts.Options) {
+			if cfg.EndpointURL != "" {
+				o.BaseEndpoint = aws.String(cfg.EndpointURL)
+			}
+		})
+		provider := stscreds.NewAssumeRoleProvider(stsClient, cfg.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if cfg.ExternalID != "" {
+				o.ExternalID = aws.String(cfg.ExternalID)
+			}
+			if cfg.RoleSessionName != "" {
+				o.RoleSessionName = cfg.RoleSessionName
+			}
+			if cfg.RoleDuration != 0 {
+				o.Duration = cfg.RoleDuration
+			}
+		})
+		awsCfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	clientOptFns := cfg.OptFns
+	if cfg.EndpointURL != "" {
+		clientOptFns = append([]func(*cloudwatchlogs.Options){func(o *cloudwatchlogs.Options) {
+			o.BaseEndpoint = aws.String(cfg.EndpointURL)
+		}}, clientOptFns...)
+	}
+	return cloudwatchlogs.NewFromConfig(awsCfg, clientOptFns...)
 }
@@ -0,0 +1,93 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testClientConstructorCalls atomic.Int32
+
+func init() {
+	RegisterClientConstructor("test_register", func(ctx context.Context, cfg *CloudwatchLogsInsightsConfig) (CloudwatchLogsClient, error) {
+		testClientConstructorCalls.Add(1)
+		return &mockCloudWatchLogsClient{}, nil
+	})
+}
+
+func TestRegisterClientConstructor(t *testing.T) {
+	testClientConstructorCalls.Store(0)
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=test_register")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if n := testClientConstructorCalls.Load(); n != 1 {
+		t.Errorf("unexpected call count: %d", n)
+	}
+
+	unknown, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unknown.Close()
+	if err := unknown.Ping(); err == nil {
+		t.Error("expected error for the unregistered client constructor")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for the duplicated registration")
+		}
+	}()
+	RegisterClientConstructor("test_register", func(ctx context.Context, cfg *CloudwatchLogsInsightsConfig) (CloudwatchLogsClient, error) {
+		return nil, nil
+	})
+}
+
+func TestConnector__SharedClient(t *testing.T) {
+	cases := []struct {
+		dsn      string
+		expected int32
+	}{
+		{dsn: "cloudwatch://?client=test_register", expected: 1},
+		{dsn: "cloudwatch://?client=test_register&client_refresh_interval=1ms", expected: 3},
+	}
+	for _, c := range cases {
+		t.Run(c.dsn, func(t *testing.T) {
+			testClientConstructorCalls.Store(0)
+			db, err := sql.Open("cloudwatch-logs-insights", c.dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxIdleConns(0)
+			var wg sync.WaitGroup
+			for i := 0; i < 3; i++ {
+				time.Sleep(5 * time.Millisecond)
+				conn, err := db.Conn(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := conn.PingContext(context.Background()); err != nil {
+						t.Error(err)
+					}
+					conn.Close()
+				}()
+			}
+			wg.Wait()
+			if n := testClientConstructorCalls.Load(); n != c.expected {
+				t.Errorf("unexpected call count: %d", n)
+			}
+		})
+	}
+}
@@ -2,23 +2,83 @@ package cloudwatchlogsinsightsdriver
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // CloudwatchLogsConfig is the configuration for the Cloudwatch Logs Insights.
 type CloudwatchLogsInsightsConfig struct {
 	OptFns        []func(*cloudwatchlogs.Options)
-	Timeout       time.Duration // Default: 10s
+	Timeout       time.Duration // Default: 10s, the default of StartTimeout and WaitTimeout
 	Polling       time.Duration // Default: 100ms
 	LogGroupNames []string
 	Region        string
 	Limit         *int32
+	DefaultRange  time.Duration  // Default: 15m
+	Location      *time.Location // Default: time.Local
+
+	Profile     string
+	EndpointURL string // Default: the endpoint of the region
+
+	RoleARN         string
+	ExternalID      string
+	RoleSessionName string        // Default: generated by the SDK
+	RoleDuration    time.Duration // Default: 15m
+
+	RetryMaxAttempts int           // Default: 0 (the SDK default)
+	RetryMode        aws.RetryMode // Default: the SDK default
+
+	ClientName            string        // Default: "" (DefaultCloudwatchLogsClientConstructor)
+	ClientRefreshInterval time.Duration // Default: 0 (the client is constructed once per connector)
+
+	LogGroupPrefix   string
+	LogGroupPattern  string
+	LogGroupCacheTTL time.Duration // Default: 1m, NoLogGroupCache (negative) disables the cache
+	SourceAccountIDs []string
+
+	SplitInterval time.Duration // Default: 0 (disabled)
+	Concurrency   int           // Default: 1, and up to 4 for the shards of more than 50 log groups
+
+	PartitionInterval  time.Duration // Default: 0 (disabled)
+	PartitionLogGroups int           // Default: 0 (disabled)
+
+	ShardErrorPolicy ShardErrorPolicy // Default: fail_fast
+
+	MaxBytesScanned   int64            // Default: 0 (unlimited)
+	MaxRecordsScanned int64            // Default: 0 (unlimited)
+	ScanBudgetPolicy  ScanBudgetPolicy // Default: error
+
+	StartTimeout time.Duration // Default: Timeout
+	WaitTimeout  time.Duration // Default: Timeout
+	StopTimeout  time.Duration // Default: 5s
+
+	MaxConcurrentQueries int // Default: 0 (unlimited)
+
+	PollStrategy         PollStrategy  // Default: FixedPollStrategy with Polling
+	PollMaxInterval      time.Duration // Default: 5s
+	GetQueryResultsRate  float64       // Default: 0 (unlimited)
+	GetQueryResultsBurst int           // Default: 1
+
+	StartRetry RetryPolicy // Default: DefaultStartRetryPolicy
+	PollRetry  RetryPolicy // Default: DefaultPollRetryPolicy
+
+	// Logger is the structured logger of the connector. Default: the logger that writes to GetLogger and GetDebugLogger.
+	Logger *slog.Logger
+
+	// TracerProvider and MeterProvider enable the OpenTelemetry instrumentation. Default: nil (disabled)
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
 
 	Params url.Values
 }
@@ -37,8 +97,59 @@ type CloudwatchLogsInsightsConfig struct {
 //		Limit:         100,
 //	}
 //
+// timeout is the default of start_timeout (StartQuery) and wait_timeout (waiting for the results).
+// stop_timeout (default: 5s) is the timeout of StopQuery for an aborted query, which is called even if the context is done.
+// The timeout named parameter limits the whole query and replaces start_timeout and wait_timeout, for example sql.Named("timeout", 5*time.Minute).
+//
+// default_range (or since) is the query time range when start_time is not specified, for example default_range=1h or since=7d.
+// timezone is the IANA time zone for the start_time and end_time without time zone, such as 2022-09-16 and today.
+//
 // Also, you can specify log_group_name instead of log_group_names.
 // However, you can not specify log_group_name and log_group_names at the same time.
+//
+// log_group_prefix or log_group_pattern resolves the log groups by DescribeLogGroups,
+// and the resolved log groups are cached for log_group_cache_ttl (default: 1m).
+// For example, cloudwatch://?log_group_prefix=/aws/lambda/billing-
+// source_account_ids also resolves the log groups in the linked source accounts, `*` means all linked accounts.
+//
+// In a monitoring account of cross-account observability, log groups can be specified by ARN or account_id:name,
+// for example, cloudwatch://?log_group_names=123456789012:/aws/lambda/hoge,arn:aws:logs:ap-northeast-1:210987654321:log-group:/aws/lambda/bar
+// These are queried as LogGroupIdentifiers, and can not be mixed with log group names.
+//
+// If split_interval is specified, the query time range is split into windows of that interval,
+// and the windows are queried with at most concurrency queries in parallel.
+// For example, cloudwatch://?log_group_name=/aws/lambda/hoge&split_interval=1h&concurrency=4
+//
+// If partition_interval or partition_log_groups is specified, a stats query is executed as partial queries
+// for each time window and each batch of log groups, and the partial results are re-aggregated.
+//
+// More than 50 log groups are searched by multiple queries of at most 50 log groups, called shards.
+// shard_error_policy=partial returns the results of the succeeded shards instead of failing fast.
+//
+// max_bytes_scanned and max_records_scanned stop the query when the total scanned bytes or records exceed the budget.
+// By default ScanBudgetExceededError is returned, scan_budget_policy=partial returns the partial results instead.
+//
+// max_concurrent_queries caps the in-flight queries of the connector (sql.DB), and the other queries wait in order of
+// the priority named parameter.
+//
+// poll_strategy selects the interval of GetQueryResults calls: fixed (default) polls at the polling interval,
+// exponential doubles the interval from polling up to poll_max_interval, and statistics adapts the interval to
+// the elapsed time and the progress of the query statistics.
+// get_query_results_rate (calls per second) and get_query_results_burst limit GetQueryResults calls of the connector (sql.DB).
+//
+// StartQuery and GetQueryResults are retried with exponential backoff and jitter for throttling errors.
+// The retry policies are set by start_max_attempts, start_retry_base_delay, start_retry_max_delay,
+// poll_max_attempts, poll_retry_base_delay and poll_retry_max_delay.
+// start_timeout limits each attempt of StartQuery, and the backoff is limited by the context and the timeout named parameter.
+//
+// profile selects the shared config profile, and role_arn assumes the role with external_id, session_name and role_duration.
+// endpoint_url overrides the endpoint of CloudWatch Logs and STS (for role_arn), for example cloudwatch://?endpoint_url=http://localhost:4566
+// retry_max_attempts and retry_mode (standard or adaptive) configure the retryer of the AWS SDK.
+// The SDK does not retry StartQuery and GetQueryResults for the throttling errors retried by the retry policies above.
+//
+// client selects the client constructor registered by RegisterClientConstructor, for example cloudwatch://?client=cached
+// The client is constructed once and shared by the connections of the connector (sql.DB),
+// and client_refresh_interval reconstructs it periodically, for example to reload the config and the credentials.
 func ParseDSN(dsn string) (*CloudwatchLogsInsightsConfig, error) {
 	cfg := &CloudwatchLogsInsightsConfig{
 		OptFns: []func(*cloudwatchlogs.Options){},
@@ -66,21 +177,84 @@ func ParseDSN(dsn string) (*CloudwatchLogsInsightsConfig, error) {
 	} else {
 		cfg.Region = os.Getenv("AWS_REGION")
 	}
+	if v := q.Get("client"); v != "" {
+		cfg.ClientName = v
+		q.Del("client")
+	}
+	if v := q.Get("client_refresh_interval"); v != "" {
+		if cfg.ClientRefreshInterval, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+		q.Del("client_refresh_interval")
+	}
+	if v := q.Get("profile"); v != "" {
+		cfg.Profile = v
+		q.Del("profile")
+	}
+	if v := q.Get("endpoint_url"); v != "" {
+		cfg.EndpointURL = v
+		q.Del("endpoint_url")
+	}
+	if v := q.Get("role_arn"); v != "" {
+		cfg.RoleARN = v
+		q.Del("role_arn")
+	}
+	if v := q.Get("external_id"); v != "" {
+		cfg.ExternalID = v
+		q.Del("external_id")
+	}
+	if v := q.Get("session_name"); v != "" {
+		cfg.RoleSessionName = v
+		q.Del("session_name")
+	}
+	if v := q.Get("role_duration"); v != "" {
+		if cfg.RoleDuration, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+		q.Del("role_duration")
+	}
+	if v := q.Get("retry_max_attempts"); v != "" {
+		i, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RetryMaxAttempts = int(i)
+		q.Del("retry_max_attempts")
+	}
+	if v := q.Get("retry_mode"); v != "" {
+		if cfg.RetryMode, err = aws.ParseRetryMode(v); err != nil {
+			return nil, err
+		}
+		q.Del("retry_mode")
+	}
 	if v := q.Get("timeout"); v != "" {
 		if cfg.Timeout, err = time.ParseDuration(v); err != nil {
 			return nil, err
 		}
 		q.Del("timeout")
-	} else {
-		cfg.Timeout = 10 * time.Second
 	}
 	if v := q.Get("polling"); v != "" {
 		if cfg.Polling, err = time.ParseDuration(v); err != nil {
 			return nil, err
 		}
 		q.Del("polling")
-	} else {
-		cfg.Polling = 100 * time.Millisecond
+	}
+	for _, key := range []string{"start_timeout", "wait_timeout", "stop_timeout"} {
+		if v := q.Get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, err
+			}
+			switch key {
+			case "start_timeout":
+				cfg.StartTimeout = d
+			case "wait_timeout":
+				cfg.WaitTimeout = d
+			case "stop_timeout":
+				cfg.StopTimeout = d
+			}
+			q.Del(key)
+		}
 	}
 	if v := q.Get("limit"); v != "" {
 		i, err := strconv.ParseUint(v, 10, 32)
@@ -92,6 +266,122 @@ func ParseDSN(dsn string) (*CloudwatchLogsInsightsConfig, error) {
 	} else {
 		cfg.Limit = nil
 	}
+	if v, since := q.Get("default_range"), q.Get("since"); v != "" || since != "" {
+		if v != "" && since != "" {
+			return nil, errors.New("can not set default_range and since at the same time")
+		}
+		if v == "" {
+			v = since
+		}
+		if cfg.DefaultRange, err = parseExtendedDuration(v); err != nil {
+			return nil, err
+		}
+		q.Del("default_range")
+		q.Del("since")
+	}
+	if v := q.Get("timezone"); v != "" {
+		if cfg.Location, err = time.LoadLocation(v); err != nil {
+			return nil, err
+		}
+		q.Del("timezone")
+	}
+	if v := q.Get("split_interval"); v != "" {
+		if cfg.SplitInterval, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+		q.Del("split_interval")
+	}
+	if v := q.Get("concurrency"); v != "" {
+		i, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			return nil, errors.New("concurrency must be positive")
+		}
+		cfg.Concurrency = int(i)
+		q.Del("concurrency")
+	}
+	if v := q.Get("partition_interval"); v != "" {
+		if cfg.PartitionInterval, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+		q.Del("partition_interval")
+	}
+	if v := q.Get("partition_log_groups"); v != "" {
+		i, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			return nil, errors.New("partition_log_groups must be positive")
+		}
+		cfg.PartitionLogGroups = int(i)
+		q.Del("partition_log_groups")
+	}
+	if v := q.Get("shard_error_policy"); v != "" {
+		if cfg.ShardErrorPolicy, err = parseShardErrorPolicy(v); err != nil {
+			return nil, err
+		}
+		q.Del("shard_error_policy")
+	}
+	for _, key := range []string{"max_bytes_scanned", "max_records_scanned"} {
+		if v := q.Get(key); v != "" {
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if key == "max_bytes_scanned" {
+				cfg.MaxBytesScanned = i
+			} else {
+				cfg.MaxRecordsScanned = i
+			}
+			q.Del(key)
+		}
+	}
+	if v := q.Get("scan_budget_policy"); v != "" {
+		if cfg.ScanBudgetPolicy, err = parseScanBudgetPolicy(v); err != nil {
+			return nil, err
+		}
+		q.Del("scan_budget_policy")
+	}
+	if v := q.Get("max_concurrent_queries"); v != "" {
+		i, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MaxConcurrentQueries = int(i)
+		q.Del("max_concurrent_queries")
+	}
+	if v := q.Get("poll_max_interval"); v != "" {
+		if cfg.PollMaxInterval, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+		q.Del("poll_max_interval")
+	}
+	// the poll strategy is built after setDefaults, with the default polling and poll_max_interval.
+	pollStrategy := q.Get("poll_strategy")
+	q.Del("poll_strategy")
+	if v := q.Get("get_query_results_rate"); v != "" {
+		if cfg.GetQueryResultsRate, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, err
+		}
+		q.Del("get_query_results_rate")
+	}
+	if v := q.Get("get_query_results_burst"); v != "" {
+		i, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetQueryResultsBurst = int(i)
+		q.Del("get_query_results_burst")
+	}
+	if cfg.StartRetry, err = parseRetryPolicy(q, "start", DefaultStartRetryPolicy); err != nil {
+		return nil, err
+	}
+	if cfg.PollRetry, err = parseRetryPolicy(q, "poll", DefaultPollRetryPolicy); err != nil {
+		return nil, err
+	}
 	if v := q.Get("log_group_names"); v != "" {
 		cfg.LogGroupNames = strings.Split(v, ",")
 		q.Del("log_group_names")
@@ -103,7 +393,37 @@ func ParseDSN(dsn string) (*CloudwatchLogsInsightsConfig, error) {
 		cfg.LogGroupNames = []string{v}
 		q.Del("log_group_name")
 	}
+	if v := q.Get("log_group_prefix"); v != "" {
+		cfg.LogGroupPrefix = v
+		q.Del("log_group_prefix")
+	}
+	if v := q.Get("log_group_pattern"); v != "" {
+		cfg.LogGroupPattern = v
+		q.Del("log_group_pattern")
+	}
+	if v := q.Get("source_account_ids"); v != "" {
+		cfg.SourceAccountIDs = strings.Split(v, ",")
+		q.Del("source_account_ids")
+	}
+	if v := q.Get("log_group_cache_ttl"); v != "" {
+		if cfg.LogGroupCacheTTL, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+		if cfg.LogGroupCacheTTL <= 0 {
+			cfg.LogGroupCacheTTL = NoLogGroupCache
+		}
+		q.Del("log_group_cache_ttl")
+	}
 	cfg.Params = q
+	cfg.setDefaults()
+	if pollStrategy != "" {
+		if cfg.PollStrategy, err = newPollStrategy(pollStrategy, cfg.Polling, cfg.PollMaxInterval); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -115,15 +435,103 @@ func (cfg *CloudwatchLogsInsightsConfig) String() string {
 	if cfg.Region != "" {
 		values.Set("region", cfg.Region)
 	}
+	if cfg.ClientName != "" {
+		values.Set("client", cfg.ClientName)
+	}
+	if cfg.ClientRefreshInterval != 0 {
+		values.Set("client_refresh_interval", cfg.ClientRefreshInterval.String())
+	}
+	if cfg.Profile != "" {
+		values.Set("profile", cfg.Profile)
+	}
+	if cfg.EndpointURL != "" {
+		values.Set("endpoint_url", cfg.EndpointURL)
+	}
+	if cfg.RoleARN != "" {
+		values.Set("role_arn", cfg.RoleARN)
+	}
+	if cfg.ExternalID != "" {
+		values.Set("external_id", cfg.ExternalID)
+	}
+	if cfg.RoleSessionName != "" {
+		values.Set("session_name", cfg.RoleSessionName)
+	}
+	if cfg.RoleDuration != 0 {
+		values.Set("role_duration", cfg.RoleDuration.String())
+	}
+	if cfg.RetryMaxAttempts != 0 {
+		values.Set("retry_max_attempts", strconv.Itoa(cfg.RetryMaxAttempts))
+	}
+	if cfg.RetryMode != "" {
+		values.Set("retry_mode", string(cfg.RetryMode))
+	}
 	if cfg.Timeout != 0 {
 		values.Set("timeout", cfg.Timeout.String())
 	}
 	if cfg.Polling != 0 {
 		values.Set("polling", cfg.Polling.String())
 	}
+	if cfg.StartTimeout != 0 {
+		values.Set("start_timeout", cfg.StartTimeout.String())
+	}
+	if cfg.WaitTimeout != 0 {
+		values.Set("wait_timeout", cfg.WaitTimeout.String())
+	}
+	if cfg.StopTimeout != 0 {
+		values.Set("stop_timeout", cfg.StopTimeout.String())
+	}
 	if cfg.Limit != nil {
 		values.Set("limit", strconv.FormatInt(int64(*cfg.Limit), 10))
 	}
+	if cfg.DefaultRange != 0 {
+		values.Set("default_range", cfg.DefaultRange.String())
+	}
+	if cfg.Location != nil && cfg.Location != time.Local {
+		values.Set("timezone", cfg.Location.String())
+	}
+	if cfg.SplitInterval != 0 {
+		values.Set("split_interval", cfg.SplitInterval.String())
+	}
+	if cfg.Concurrency != 0 {
+		values.Set("concurrency", strconv.Itoa(cfg.Concurrency))
+	}
+	if cfg.PartitionInterval != 0 {
+		values.Set("partition_interval", cfg.PartitionInterval.String())
+	}
+	if cfg.PartitionLogGroups != 0 {
+		values.Set("partition_log_groups", strconv.Itoa(cfg.PartitionLogGroups))
+	}
+	if cfg.ShardErrorPolicy != "" && cfg.ShardErrorPolicy != ShardErrorPolicyFailFast {
+		values.Set("shard_error_policy", string(cfg.ShardErrorPolicy))
+	}
+	if cfg.MaxBytesScanned != 0 {
+		values.Set("max_bytes_scanned", strconv.FormatInt(cfg.MaxBytesScanned, 10))
+	}
+	if cfg.MaxRecordsScanned != 0 {
+		values.Set("max_records_scanned", strconv.FormatInt(cfg.MaxRecordsScanned, 10))
+	}
+	if cfg.ScanBudgetPolicy != "" && cfg.ScanBudgetPolicy != ScanBudgetPolicyError {
+		values.Set("scan_budget_policy", string(cfg.ScanBudgetPolicy))
+	}
+	if cfg.MaxConcurrentQueries != 0 {
+		values.Set("max_concurrent_queries", strconv.Itoa(cfg.MaxConcurrentQueries))
+	}
+	if cfg.PollStrategy != nil {
+		if name := pollStrategyName(cfg.PollStrategy); name != "" {
+			values.Set("poll_strategy", name)
+		}
+	}
+	if cfg.PollMaxInterval != 0 {
+		values.Set("poll_max_interval", cfg.PollMaxInterval.String())
+	}
+	if cfg.GetQueryResultsRate != 0 {
+		values.Set("get_query_results_rate", strconv.FormatFloat(cfg.GetQueryResultsRate, 'f', -1, 64))
+	}
+	if cfg.GetQueryResultsBurst != 0 {
+		values.Set("get_query_results_burst", strconv.Itoa(cfg.GetQueryResultsBurst))
+	}
+	setRetryPolicy(values, "start", cfg.StartRetry)
+	setRetryPolicy(values, "poll", cfg.PollRetry)
 	if len(cfg.LogGroupNames) > 0 {
 		if len(cfg.LogGroupNames) == 1 {
 			values.Set("log_group_name", cfg.LogGroupNames[0])
@@ -131,5 +539,194 @@ func (cfg *CloudwatchLogsInsightsConfig) String() string {
 			values.Set("log_group_names", strings.Join(cfg.LogGroupNames, ","))
 		}
 	}
+	if cfg.LogGroupPrefix != "" {
+		values.Set("log_group_prefix", cfg.LogGroupPrefix)
+	}
+	if cfg.LogGroupPattern != "" {
+		values.Set("log_group_pattern", cfg.LogGroupPattern)
+	}
+	if len(cfg.SourceAccountIDs) > 0 {
+		values.Set("source_account_ids", strings.Join(cfg.SourceAccountIDs, ","))
+	}
+	switch {
+	case cfg.LogGroupCacheTTL < 0:
+		values.Set("log_group_cache_ttl", "0")
+	case cfg.LogGroupCacheTTL != 0 && cfg.LogGroupCacheTTL != defaultLogGroupCacheTTL:
+		values.Set("log_group_cache_ttl", cfg.LogGroupCacheTTL.String())
+	}
 	return "cloudwatch://?" + values.Encode()
 }
+
+func parseRetryPolicy(q url.Values, prefix string, policy RetryPolicy) (RetryPolicy, error) {
+	var err error
+	if v := q.Get(prefix + "_max_attempts"); v != "" {
+		i, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return policy, err
+		}
+		policy.MaxAttempts = int(i)
+		q.Del(prefix + "_max_attempts")
+	}
+	if v := q.Get(prefix + "_retry_base_delay"); v != "" {
+		if policy.BaseDelay, err = time.ParseDuration(v); err != nil {
+			return policy, err
+		}
+		q.Del(prefix + "_retry_base_delay")
+	}
+	if v := q.Get(prefix + "_retry_max_delay"); v != "" {
+		if policy.MaxDelay, err = time.ParseDuration(v); err != nil {
+			return policy, err
+		}
+		q.Del(prefix + "_retry_max_delay")
+	}
+	return policy, nil
+}
+
+func (policy RetryPolicy) validate(prefix string) error {
+	if policy.MaxAttempts < 0 || policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+		return fmt.Errorf("%s_max_attempts, %s_retry_base_delay and %s_retry_max_delay must be non-negative", prefix, prefix, prefix)
+	}
+	return nil
+}
+
+func setRetryPolicy(values url.Values, prefix string, policy RetryPolicy) {
+	if policy.MaxAttempts != 0 {
+		values.Set(prefix+"_max_attempts", strconv.Itoa(policy.MaxAttempts))
+	}
+	if policy.BaseDelay != 0 {
+		values.Set(prefix+"_retry_base_delay", policy.BaseDelay.String())
+	}
+	if policy.MaxDelay != 0 {
+		values.Set(prefix+"_retry_max_delay", policy.MaxDelay.String())
+	}
+}
+
+// validate checks the config after setDefaults. It is called by ParseDSN and NewConnector.
+func (cfg *CloudwatchLogsInsightsConfig) validate() error {
+	if cfg.EndpointURL != "" {
+		if _, err := url.ParseRequestURI(cfg.EndpointURL); err != nil {
+			return fmt.Errorf("endpoint_url:%w", err)
+		}
+	}
+	if cfg.RoleARN == "" && (cfg.ExternalID != "" || cfg.RoleSessionName != "" || cfg.RoleDuration != 0) {
+		return errors.New("external_id, session_name and role_duration require role_arn")
+	}
+	if cfg.RetryMaxAttempts < 0 {
+		return errors.New("retry_max_attempts must be non-negative")
+	}
+	if cfg.RetryMode != "" {
+		if _, err := aws.ParseRetryMode(string(cfg.RetryMode)); err != nil {
+			return err
+		}
+	}
+	if cfg.Limit != nil && (*cfg.Limit <= 0 || *cfg.Limit > maxQueryResults) {
+		return fmt.Errorf("limit must be between 1 and %d", maxQueryResults)
+	}
+	if cfg.Polling < 0 || cfg.PollMaxInterval < 0 {
+		return errors.New("polling and poll_max_interval must be positive")
+	}
+	if cfg.SplitInterval != 0 && cfg.SplitInterval < time.Second {
+		return errors.New("split_interval must be at least 1s")
+	}
+	if cfg.Concurrency < 0 {
+		return errors.New("concurrency must be positive")
+	}
+	if cfg.PartitionInterval != 0 && cfg.PartitionInterval < time.Second {
+		return errors.New("partition_interval must be at least 1s")
+	}
+	if cfg.PartitionLogGroups < 0 {
+		return errors.New("partition_log_groups must be positive")
+	}
+	if _, err := parseShardErrorPolicy(string(cfg.ShardErrorPolicy)); err != nil {
+		return err
+	}
+	if cfg.MaxBytesScanned < 0 || cfg.MaxRecordsScanned < 0 {
+		return errors.New("max_bytes_scanned and max_records_scanned must be non-negative")
+	}
+	if _, err := parseScanBudgetPolicy(string(cfg.ScanBudgetPolicy)); err != nil {
+		return err
+	}
+	if cfg.MaxConcurrentQueries < 0 {
+		return errors.New("max_concurrent_queries must be non-negative")
+	}
+	if cfg.GetQueryResultsRate < 0 || math.IsNaN(cfg.GetQueryResultsRate) {
+		return errors.New("get_query_results_rate must be non-negative")
+	}
+	if cfg.GetQueryResultsBurst < 0 {
+		return errors.New("get_query_results_burst must be non-negative")
+	}
+	if err := cfg.StartRetry.validate("start"); err != nil {
+		return err
+	}
+	if err := cfg.PollRetry.validate("poll"); err != nil {
+		return err
+	}
+	if cfg.LogGroupPrefix != "" && cfg.LogGroupPattern != "" {
+		return errors.New("can not set log_group_prefix and log_group_pattern at the same time")
+	}
+	return nil
+}
+
+func (cfg *CloudwatchLogsInsightsConfig) startTimeout() time.Duration {
+	if cfg.StartTimeout > 0 {
+		return cfg.StartTimeout
+	}
+	return cfg.Timeout
+}
+
+func (cfg *CloudwatchLogsInsightsConfig) waitTimeout() time.Duration {
+	if cfg.WaitTimeout > 0 {
+		return cfg.WaitTimeout
+	}
+	return cfg.Timeout
+}
+
+func (cfg *CloudwatchLogsInsightsConfig) stopTimeout() time.Duration {
+	if cfg.StopTimeout > 0 {
+		return cfg.StopTimeout
+	}
+	return 5 * time.Second
+}
+
+// setDefaults replaces the zero values with the defaults.
+func (cfg *CloudwatchLogsInsightsConfig) setDefaults() {
+	if cfg.OptFns == nil {
+		cfg.OptFns = []func(*cloudwatchlogs.Options){}
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Polling == 0 {
+		cfg.Polling = 100 * time.Millisecond
+	}
+	if cfg.StopTimeout == 0 {
+		cfg.StopTimeout = 5 * time.Second
+	}
+	if cfg.DefaultRange == 0 {
+		cfg.DefaultRange = 15 * time.Minute
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.LogGroupCacheTTL == 0 {
+		cfg.LogGroupCacheTTL = defaultLogGroupCacheTTL
+	}
+	if cfg.ShardErrorPolicy == "" {
+		cfg.ShardErrorPolicy = ShardErrorPolicyFailFast
+	}
+	if cfg.ScanBudgetPolicy == "" {
+		cfg.ScanBudgetPolicy = ScanBudgetPolicyError
+	}
+	if cfg.PollMaxInterval == 0 {
+		cfg.PollMaxInterval = 5 * time.Second
+	}
+	if cfg.GetQueryResultsBurst == 0 {
+		cfg.GetQueryResultsBurst = 1
+	}
+	if cfg.StartRetry == (RetryPolicy{}) {
+		cfg.StartRetry = DefaultStartRetryPolicy
+	}
+	if cfg.PollRetry == (RetryPolicy{}) {
+		cfg.PollRetry = DefaultPollRetryPolicy
+	}
+}
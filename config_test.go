@@ -1,8 +1,19 @@
 package cloudwatchlogsinsightsdriver
 
 import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 )
 
 func TestConfgParseDSN(t *testing.T) {
@@ -36,3 +47,151 @@ func TestConfgParseDSN(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigParseDSN__LogGroupCacheTTL(t *testing.T) {
+	for dsn, expected := range map[string]time.Duration{
+		"cloudwatch://?log_group_name=test":                        time.Minute,
+		"cloudwatch://?log_group_name=test&log_group_cache_ttl=0":  NoLogGroupCache,
+		"cloudwatch://?log_group_name=test&log_group_cache_ttl=5m": 5 * time.Minute,
+	} {
+		cfg, err := ParseDSN(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg, err = ParseDSN(cfg.String()); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.LogGroupCacheTTL != expected {
+			t.Errorf("%s: expected %s, got %s", dsn, expected, cfg.LogGroupCacheTTL)
+		}
+	}
+}
+
+func TestConfigParseDSN__Credentials(t *testing.T) {
+	dsn := "cloudwatch://?profile=dev&role_arn=arn:aws:iam::123456789012:role/reader&external_id=ext&session_name=driver&role_duration=1h" +
+		"&endpoint_url=http://localhost:4566&retry_max_attempts=10&retry_mode=adaptive"
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if cfg.Profile != "dev" || cfg.RoleARN != "arn:aws:iam::123456789012:role/reader" || cfg.ExternalID != "ext" ||
+			cfg.RoleSessionName != "driver" || cfg.RoleDuration != time.Hour || cfg.EndpointURL != "http://localhost:4566" ||
+			cfg.RetryMaxAttempts != 10 || cfg.RetryMode != aws.RetryModeAdaptive {
+			t.Fatalf("unexpected config: %#v", cfg)
+		}
+		if cfg, err = ParseDSN(cfg.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dsn := range []string{
+		"cloudwatch://?external_id=ext",
+		"cloudwatch://?retry_mode=unknown",
+		"cloudwatch://?endpoint_url=localhost",
+	} {
+		if _, err := ParseDSN(dsn); err == nil {
+			t.Errorf("%s: expected error", dsn)
+		}
+	}
+}
+
+func TestDefaultCloudwatchLogsClientConstructor__EndpointURL(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "Logs_20140328.DescribeLogGroups" {
+			t.Errorf("unexpected target: %s", target)
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Write([]byte(`{"logGroups":[{"logGroupName":"test-log-group"}]}`))
+	}))
+	defer server.Close()
+	cfg, err := ParseDSN("cloudwatch://?region=us-east-1&endpoint_url=" + url.QueryEscape(server.URL) + "&retry_max_attempts=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DefaultCloudwatchLogsClientConstructor(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := client.DescribeLogGroups(context.Background(), &cloudwatchlogs.DescribeLogGroupsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.LogGroups) != 1 || coalesce(output.LogGroups[0].LogGroupName) != "test-log-group" {
+		t.Fatal("unexpected log groups:", output.LogGroups)
+	}
+}
+
+func TestDefaultCloudwatchLogsClientConstructor__EndpointURL__AssumeRole(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	var assumed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") == "" {
+			if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "AssumeRole" {
+				t.Errorf("unexpected request: %s %v", r.URL, r.Form)
+			}
+			assumed.Store(true)
+			w.Header().Set("Content-Type", "text/xml")
+			w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>` +
+				`<Credentials><AccessKeyId>assumed</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>2099-01-01T00:00:00Z</Expiration></Credentials>` +
+				`<AssumedRoleUser><Arn>arn:aws:sts::123456789012:assumed-role/reader/driver</Arn><AssumedRoleId>AROA:driver</AssumedRoleId></AssumedRoleUser>` +
+				`</AssumeRoleResult></AssumeRoleResponse>`))
+			return
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=assumed/") {
+			t.Errorf("unexpected authorization: %s", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Write([]byte(`{"logGroups":[]}`))
+	}))
+	defer server.Close()
+	cfg, err := ParseDSN("cloudwatch://?region=us-east-1&role_arn=arn:aws:iam::123456789012:role/reader&endpoint_url=" + url.QueryEscape(server.URL) + "&retry_max_attempts=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DefaultCloudwatchLogsClientConstructor(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DescribeLogGroups(context.Background(), &cloudwatchlogs.DescribeLogGroupsInput{}); err != nil {
+		t.Fatal(err)
+	}
+	if !assumed.Load() {
+		t.Fatal("AssumeRole is not called on endpoint_url")
+	}
+}
+
+func TestQueryContext__WithEndpointURL__RetryNotStacked(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"ThrottlingException","message":"Rate exceeded"}`))
+	}))
+	defer server.Close()
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?region=us-east-1&log_group_name=test-log-group&endpoint_url="+url.QueryEscape(server.URL)+
+		"&start_max_attempts=2&start_retry_base_delay=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.QueryContext(context.Background(), "fields @message")
+	if err == nil {
+		t.Fatal("unexpected nil error")
+	}
+	t.Log(err)
+	if n := requests.Load(); n != 2 {
+		t.Fatal("unexpected StartQuery requests:", n)
+	}
+}
@@ -3,8 +3,9 @@ package cloudwatchlogsinsightsdriver
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"fmt"
-	"strings"
+	"log/slog"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -13,22 +14,36 @@ import (
 )
 
 type cloudwatchLogsInsightsConn struct {
-	client   CloudwatchLogsClient
-	cfg      *CloudwatchLogsInsightsConfig
-	aliveCh  chan struct{}
-	isClosed bool
+	client     CloudwatchLogsClient
+	cfg        *CloudwatchLogsInsightsConfig
+	connector  *cloudwatchLogsInsightsConnector
+	aliveCh    chan struct{}
+	isClosed   bool
+	statistics []QueryStatistics
+	logger     *slog.Logger
 }
 
-func newConn(client CloudwatchLogsClient, cfg *CloudwatchLogsInsightsConfig) *cloudwatchLogsInsightsConn {
+func newConn(client CloudwatchLogsClient, connector *cloudwatchLogsInsightsConnector) *cloudwatchLogsInsightsConn {
 	return &cloudwatchLogsInsightsConn{
-		client:  client,
-		cfg:     cfg,
-		aliveCh: make(chan struct{}),
+		client:    client,
+		cfg:       connector.cfg,
+		connector: connector,
+		aliveCh:   make(chan struct{}),
+		logger:    connector.logger(),
 	}
 }
 
 func (conn *cloudwatchLogsInsightsConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
-	return nil, fmt.Errorf("prepared statment %w", ErrNotSupported)
+	if conn.isClosed {
+		return nil, ErrConnClosed
+	}
+	if _, err := scanPlaceholders(query); err != nil {
+		return nil, fmt.Errorf("prepare statement: %w", err)
+	}
+	return &cloudwatchLogsInsightsStmt{
+		conn:  conn,
+		query: query,
+	}, nil
 }
 
 func (conn *cloudwatchLogsInsightsConn) Prepare(query string) (driver.Stmt, error) {
@@ -44,6 +59,21 @@ func (conn *cloudwatchLogsInsightsConn) Close() error {
 	return nil
 }
 
+// CheckNamedValue keeps the driver specific parameter types such as Regex, Field and time.Duration,
+// and converts the other values by the default converter.
+func (conn *cloudwatchLogsInsightsConn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch nv.Value.(type) {
+	case Regex, Field, time.Duration, []string:
+		return nil
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = v
+	return nil
+}
+
 func (conn *cloudwatchLogsInsightsConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
 	return nil, fmt.Errorf("transaction %w", ErrNotSupported)
 }
@@ -53,137 +83,342 @@ func (conn *cloudwatchLogsInsightsConn) Begin() (driver.Tx, error) {
 }
 
 func (conn *cloudwatchLogsInsightsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	endTime := time.Now()
-	startTime := endTime.Add(-15 * time.Minute)
-	var logGroupNames []string
-	limit := conn.cfg.Limit
-	var logGroupName *string
-	var err error
-	for _, arg := range args {
-		switch arg.Name {
-		case "start_time":
-			switch v := arg.Value.(type) {
-			case time.Time:
-				startTime = v
-			case string:
-				startTime, err = time.Parse(time.RFC3339, v)
-				if err != nil {
-					return nil, fmt.Errorf("start_time cannot be parsed: %w", err)
-				}
-			default:
-				return nil, fmt.Errorf("start_time must be time.Time or string")
-			}
-		case "end_time":
-			switch v := arg.Value.(type) {
-			case time.Time:
-				endTime = v
-			case string:
-				endTime, err = time.Parse(time.RFC3339, v)
-				if err != nil {
-					return nil, fmt.Errorf("end_time cannot be parsed: %w", err)
-				}
-			default:
-				return nil, fmt.Errorf("end_time must be time.Time or string")
+	opts, binds, err := newQueryOptions(conn.cfg, args)
+	if err != nil {
+		return nil, err
+	}
+	query, err = interpolate(query, binds)
+	if err != nil {
+		return nil, err
+	}
+	var cancel context.CancelFunc
+	if opts.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	var streaming bool
+	defer func() {
+		// the streaming rows use the context until they are closed.
+		if !streaming {
+			cancel()
+		}
+	}()
+	ctx = conn.connector.withHooks(ctx)
+	ctx = withQueryPriority(ctx, opts.priority)
+	ctx = withQueryTimeout(ctx, opts.timeout)
+	if opts.queryID != "" {
+		return conn.attachQuery(ctx, opts)
+	}
+	if opts.logGroupPrefix != "" || opts.logGroupPattern != "" {
+		resolved, err := conn.connector.logGroupCache.resolve(ctx, conn.client, opts.logGroupPrefix, opts.logGroupPattern, opts.sourceAccountIDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(resolved) == 0 {
+			return nil, fmt.Errorf("no log group matches log_group_prefix or log_group_pattern")
+		}
+		conn.logger.DebugContext(ctx, "resolved log groups", slog.Any(LogKeyLogGroups, resolved))
+		opts.logGroupNames = append(append([]string{}, opts.logGroupNames...), resolved...)
+	}
+	if opts.logGroupNames, err = normalizeLogGroups(opts.logGroupNames, conn.cfg.Region); err != nil {
+		return nil, err
+	}
+	if opts.noWait {
+		return conn.startQueryWithoutWaiting(ctx, query, opts)
+	}
+	if opts.stream {
+		streaming = true
+		return conn.streamQuery(ctx, cancel, query, opts)
+	}
+	ctx, rec := withStatisticsRecorder(ctx)
+	ctx = withScanBudget(ctx, opts)
+	results, err := conn.query(ctx, query, opts)
+	conn.statistics = rec.list()
+	var partial *PartialResultsError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+	rows := newRows(results)
+	rows.statistics = conn.statistics
+	if partial != nil {
+		rows.err = partial
+	}
+	return rows, nil
+}
+
+// attachQuery waits for the results of the query started before, instead of starting a new query.
+// The query is not stopped when the waiting is aborted, unless stop_on_abort is set.
+func (conn *cloudwatchLogsInsightsConn) attachQuery(ctx context.Context, opts *queryOptions) (driver.Rows, error) {
+	conn.logger.DebugContext(ctx, "attach query", slog.String(LogKeyQueryID, opts.queryID))
+	ctx, rec := withStatisticsRecorder(ctx)
+	ctx = withScanBudget(ctx, opts)
+	if err := conn.connector.limiter.acquire(ctx, opts.priority); err != nil {
+		return nil, err
+	}
+	ctx, qt := conn.connector.telemetry.startQuery(ctx, nil)
+	qt.setQueryID(aws.String(opts.queryID))
+	output, err := conn.waitQuery(ctx, aws.String(opts.queryID), nil, opts.stopOnAbort)
+	qt.end(ctx, err)
+	conn.connector.limiter.release()
+	conn.statistics = rec.list()
+	if err != nil {
+		return nil, err
+	}
+	rows := newRows(output.Results)
+	rows.statistics = conn.statistics
+	return rows, nil
+}
+
+// startQueryWithoutWaiting starts the query and returns a row with the query_id column.
+func (conn *cloudwatchLogsInsightsConn) startQueryWithoutWaiting(ctx context.Context, query string, opts *queryOptions) (driver.Rows, error) {
+	if len(opts.logGroupNames) > maxLogGroupsPerQuery || opts.splitInterval > 0 || opts.partitionInterval > 0 || opts.partitionLogGroups > 0 {
+		return nil, fmt.Errorf("wait=false can not be used with split, partitioned or more than %d log groups queries", maxLogGroupsPerQuery)
+	}
+	queryID, err := conn.startQueryNoWait(ctx, newStartQueryInput(query, opts))
+	if err != nil {
+		return nil, err
+	}
+	return newRows([][]types.ResultField{
+		{{Field: aws.String(queryIDColumn), Value: queryID}},
+	}), nil
+}
+
+// QueryStatistics implements QueryStatisticsProvider, returns the statistics of the last query.
+func (conn *cloudwatchLogsInsightsConn) QueryStatistics() []QueryStatistics {
+	return conn.statistics
+}
+
+func (conn *cloudwatchLogsInsightsConn) query(ctx context.Context, query string, opts *queryOptions) ([][]types.ResultField, error) {
+	params := newStartQueryInput(query, opts)
+	fanOut := len(opts.logGroupNames) > maxLogGroupsPerQuery
+	if fanOut || opts.splitInterval > 0 || opts.partitionInterval > 0 || opts.partitionLogGroups > 0 {
+		sq, err := parseStatsQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		if sq != nil {
+			// the windows of split_interval would return the groups per window, so re-aggregate them.
+			if opts.splitInterval > 0 && opts.partitionInterval == 0 {
+				opts.partitionInterval = opts.splitInterval
 			}
-		case "log_group_name":
-			logGroupNames = append(logGroupNames, arg.Value.(string))
-		case "log_group_names":
-			switch v := arg.Value.(type) {
-			case []string:
-				logGroupNames = append(logGroupNames, v...)
-			case string:
-				logGroupNames = append(logGroupNames, strings.Split(v, ",")...)
-			default:
-				return nil, fmt.Errorf("log_group_names must be []string or string")
+			if opts.partitionLogGroups > maxLogGroupsPerQuery || (fanOut && opts.partitionLogGroups == 0) {
+				opts.partitionLogGroups = maxLogGroupsPerQuery
 			}
-		case "limit":
-			limit = aws.Int32(int32(arg.Value.(int)))
+			return conn.partitionedStatsQuery(ctx, params, opts, sq)
 		}
 	}
-	if len(logGroupNames) == 0 {
-		if len(conn.cfg.LogGroupNames) == 0 {
-			return nil, fmt.Errorf("log_group_name is required")
+	if fanOut {
+		return conn.fanOutQuery(ctx, params, opts)
+	}
+	return conn.queryShard(ctx, params, opts)
+}
+
+// stopQuery stops the query if it is still running.
+// The context may be already done, so StopQuery is called on the detached context not to leave the query running.
+func (conn *cloudwatchLogsInsightsConn) stopQuery(ctx context.Context, queryID *string) {
+	logger := conn.logger.With(slog.String(LogKeyQueryID, coalesce(queryID, nullif("-"))))
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), conn.cfg.stopTimeout())
+	defer cancel()
+	getQueryResultsOutput, err := conn.client.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
+		QueryId: queryID,
+	})
+	if err != nil {
+		logger.DebugContext(ctx, "failed get query results for finish", slog.Any("error", err))
+	} else {
+		switch getQueryResultsOutput.Status {
+		case types.QueryStatusCancelled, types.QueryStatusFailed, types.QueryStatusComplete, types.QueryStatusTimeout, types.QueryStatusUnknown:
+			// no need cancel
+			return
 		}
-		logGroupNames = conn.cfg.LogGroupNames
 	}
-	if len(logGroupNames) == 1 {
-		logGroupName = aws.String(logGroupNames[0])
-		logGroupNames = nil
+	logger.DebugContext(ctx, "try stop query")
+	output, err := conn.client.StopQuery(ctx, &cloudwatchlogs.StopQueryInput{
+		QueryId: queryID,
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed stop query", slog.Any("error", err))
+		return
+	}
+	if !output.Success {
+		logger.DebugContext(ctx, "stop query is not success")
 	}
+}
+
+// getQueryResults calls GetQueryResults with the poll retry policy and the rate limit of the connector.
+func (conn *cloudwatchLogsInsightsConn) getQueryResults(ctx context.Context, queryID *string) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	var output *cloudwatchlogs.GetQueryResultsOutput
+	err := conn.cfg.PollRetry.do(ctx, conn.logger.With(slog.String(LogKeyQueryID, coalesce(queryID))), "get query results", func(ctx context.Context) error {
+		if err := conn.connector.pollLimiter.wait(ctx); err != nil {
+			return err
+		}
+		var err error
+		output, err = conn.client.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
+			QueryId: queryID,
+		}, withoutSDKRetry)
+		return err
+	})
+	return output, err
+}
+
+func newStartQueryInput(query string, opts *queryOptions) *cloudwatchlogs.StartQueryInput {
 	params := &cloudwatchlogs.StartQueryInput{
-		QueryString:   nullif(query),
-		StartTime:     aws.Int64(startTime.Unix()),
-		EndTime:       aws.Int64(endTime.Unix()),
-		Limit:         limit,
-		LogGroupNames: logGroupNames,
-		LogGroupName:  logGroupName,
-	}
-	output, err := conn.startQuery(ctx, params)
-	if err != nil {
-		return nil, err
+		QueryString: nullif(query),
+		StartTime:   aws.Int64(opts.startTime.Unix()),
+		EndTime:     aws.Int64(opts.endTime.Unix()),
+		Limit:       opts.limit,
+	}
+	setLogGroups(params, opts.logGroupNames)
+	return params
+}
+
+// setLogGroups sets the log groups normalized by normalizeLogGroups to LogGroupName, LogGroupNames or LogGroupIdentifiers.
+func setLogGroups(params *cloudwatchlogs.StartQueryInput, logGroups []string) {
+	params.LogGroupName = nil
+	params.LogGroupNames = nil
+	params.LogGroupIdentifiers = nil
+	switch {
+	case len(logGroups) > 0 && isLogGroupARN(logGroups[0]):
+		params.LogGroupIdentifiers = logGroups
+	case len(logGroups) == 1:
+		params.LogGroupName = aws.String(logGroups[0])
+	default:
+		params.LogGroupNames = logGroups
 	}
-	return newRows(output), nil
 }
 
 func (conn *cloudwatchLogsInsightsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	return nil, fmt.Errorf("exec statment %w", ErrNotSupported)
 }
 
-func (conn *cloudwatchLogsInsightsConn) startQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput) (*cloudwatchlogs.GetQueryResultsOutput, error) {
-	debugLogger.Printf("query: %s", coalesce(params.QueryString))
-	ectx, cancel := context.WithTimeout(ctx, conn.cfg.Timeout)
-	defer cancel()
-	startQueryOutput, err := conn.client.StartQuery(ectx, params)
+func (conn *cloudwatchLogsInsightsConn) startQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput) (output *cloudwatchlogs.GetQueryResultsOutput, err error) {
+	if err := conn.connector.limiter.acquire(ctx, getQueryPriority(ctx)); err != nil {
+		return nil, err
+	}
+	defer conn.connector.limiter.release()
+	ctx, qt := conn.connector.telemetry.startQuery(ctx, params)
+	defer func() {
+		qt.end(ctx, err)
+	}()
+	queryID, err := conn.startQueryNoWait(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	qt.setQueryID(queryID)
+	return conn.waitQuery(ctx, queryID, params, true)
+}
+
+type queryTimeoutKey struct{}
+
+// withQueryTimeout returns the context with the timeout named parameter, which replaces start_timeout and wait_timeout.
+func withQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+func (conn *cloudwatchLogsInsightsConn) startTimeout(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return conn.cfg.startTimeout()
+}
+
+func (conn *cloudwatchLogsInsightsConn) waitTimeout(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return conn.cfg.waitTimeout()
+}
+
+// startQueryNoWait starts the query and returns the query ID without waiting for the results.
+func (conn *cloudwatchLogsInsightsConn) startQueryNoWait(ctx context.Context, params *cloudwatchlogs.StartQueryInput) (*string, error) {
+	var startQueryOutput *cloudwatchlogs.StartQueryOutput
+	// start_timeout limits each attempt, so the backoff of LimitExceededException does not exhaust it.
+	err := conn.cfg.StartRetry.do(ctx, conn.logger, "start query", func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, conn.startTimeout(ctx))
+		defer cancel()
+		var err error
+		startQueryOutput, err = conn.client.StartQuery(ctx, params, withoutSDKRetry)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("start query:%w", err)
 	}
+	conn.logger.DebugContext(ctx, "start query", append(queryLogAttrs(startQueryOutput.QueryId, params), slog.String("query", coalesce(params.QueryString)))...)
+	return startQueryOutput.QueryId, nil
+}
+
+// waitQuery polls the results of the started query until it is finished.
+// If the query is not finished when returning and stopOnAbort is true, the query is stopped.
+// params is the input of the started query for errors, and nil for an attached query.
+func (conn *cloudwatchLogsInsightsConn) waitQuery(ctx context.Context, queryID *string, params *cloudwatchlogs.StartQueryInput, stopOnAbort bool) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	ectx, cancel := context.WithTimeout(ctx, conn.waitTimeout(ctx))
+	defer cancel()
 	queryStart := time.Now()
-	logPrefix := coalesce(startQueryOutput.QueryId, nullif("-"))
-	debugLogger.Printf("[%s] start query statement: %s", logPrefix, coalesce(params.QueryString))
+	logger := conn.logger.With(queryLogAttrs(queryID, params)...)
 	var isFinished bool
 	defer func() {
-		if !isFinished {
-			getQueryResultsOutput, err := conn.client.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
-				QueryId: startQueryOutput.QueryId,
-			})
-			if err != nil {
-				debugLogger.Printf("[%s] failed get query results for finish: %v", logPrefix, err)
-			} else {
-				switch getQueryResultsOutput.Status {
-				case types.QueryStatusCancelled, types.QueryStatusFailed, types.QueryStatusComplete, types.QueryStatusTimeout:
-					// no need cancel
-					return
-				}
-			}
-			debugLogger.Printf("[%s] try stop query", logPrefix)
-			output, err := conn.client.StopQuery(ctx, &cloudwatchlogs.StopQueryInput{
-				QueryId: startQueryOutput.QueryId,
-			})
-			if err != nil {
-				errLogger.Printf("[%s] failed stop query: %v", logPrefix, err)
-				return
-			}
-			if !output.Success {
-				debugLogger.Printf("[%s] stop query is not success", logPrefix)
-			}
+		if !isFinished && stopOnAbort {
+			conn.stopQuery(ctx, queryID)
 		}
 	}()
-	getQueryResultsOutput, err := conn.client.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
-		QueryId: startQueryOutput.QueryId,
-	})
+	// the last seen statistics are recorded however the query ends, for example failed, timed out or aborted.
+	var getQueryResultsOutput *cloudwatchlogs.GetQueryResultsOutput
+	defer func() {
+		if getQueryResultsOutput != nil {
+			recordQueryStatistics(ctx, newQueryStatistics(coalesce(queryID), getQueryResultsOutput.Statistics, time.Since(queryStart)))
+		}
+	}()
+	getQueryResultsOutput, err := conn.getQueryResults(ectx, queryID)
 	if err != nil {
 		return nil, fmt.Errorf("get query results:%w", err)
 	}
+	queryTelemetryFromContext(ctx).poll(ctx, getQueryResultsOutput)
+	reportQueryProgress(ctx, coalesce(queryID), getQueryResultsOutput, time.Since(queryStart))
+	pollStrategy := conn.cfg.PollStrategy
+	if pollStrategy == nil {
+		pollStrategy = FixedPollStrategy{Interval: conn.cfg.Polling}
+	}
+	pollState := PollState{Attempt: 1}
 	delay := time.NewTimer(conn.cfg.Polling)
 	for {
 		if getQueryResultsOutput.Status == types.QueryStatusComplete {
 			break
 		}
-		if getQueryResultsOutput.Status == types.QueryStatusFailed {
-			return nil, fmt.Errorf("query failed: %s", coalesce(startQueryOutput.QueryId))
+		switch getQueryResultsOutput.Status {
+		case types.QueryStatusFailed, types.QueryStatusCancelled, types.QueryStatusTimeout, types.QueryStatusUnknown:
+			isFinished = true
+			logger.WarnContext(ctx, "query is not completed", statisticsLogAttrs(getQueryResultsOutput.Status, getQueryResultsOutput.Statistics, time.Since(queryStart))...)
+			return nil, newQueryError(coalesce(queryID), getQueryResultsOutput.Status, params)
+		}
+		if budget := getScanBudget(ctx); budget != nil {
+			if err := budget.check(coalesce(queryID), getQueryResultsOutput.Statistics); err != nil {
+				// the query over the budget is stopped even if it is attached without stop_on_abort.
+				isFinished = true
+				conn.stopQuery(ctx, queryID)
+				if budget.policy != ScanBudgetPolicyPartial {
+					return nil, err
+				}
+				logger.WarnContext(ctx, "scan budget exceeded, returns partial results", append(
+					statisticsLogAttrs(getQueryResultsOutput.Status, getQueryResultsOutput.Statistics, time.Since(queryStart)),
+					slog.Any("error", err), slog.Int("result_rows", len(getQueryResultsOutput.Results)),
+				)...)
+				return getQueryResultsOutput, nil
+			}
 		}
-		debugLogger.Printf("[%s] wating finsih query: elapsed_time=%s", logPrefix, time.Since(queryStart))
-		delay.Reset(conn.cfg.Polling)
+		pollState.Elapsed = time.Since(queryStart)
+		pollState.Statistics = getQueryResultsOutput.Statistics
+		interval := pollStrategy.NextInterval(pollState)
+		pollState.PreviousInterval = interval
+		pollState.PreviousStatistics = getQueryResultsOutput.Statistics
+		pollState.Attempt++
+		logger.DebugContext(ctx, "waiting for query", append(
+			statisticsLogAttrs(getQueryResultsOutput.Status, getQueryResultsOutput.Statistics, time.Since(queryStart)),
+			slog.Duration("next_poll", interval),
+		)...)
+		delay.Reset(interval)
 		select {
 		case <-ectx.Done():
 			if !delay.Stop() {
@@ -197,15 +432,18 @@ func (conn *cloudwatchLogsInsightsConn) startQuery(ctx context.Context, params *
 			}
 			return nil, ErrConnClosed
 		}
-		getQueryResultsOutput, err = conn.client.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
-			QueryId: startQueryOutput.QueryId,
-		})
+		output, err := conn.getQueryResults(ectx, queryID)
 		if err != nil {
 			return nil, fmt.Errorf("get query results:%w", err)
 		}
+		getQueryResultsOutput = output
+		queryTelemetryFromContext(ctx).poll(ctx, getQueryResultsOutput)
+		reportQueryProgress(ctx, coalesce(queryID), getQueryResultsOutput, time.Since(queryStart))
 	}
 	isFinished = true
-	debugLogger.Printf("[%s] success query: elapsed_time=%s", logPrefix, time.Since(queryStart))
-	debugLogger.Printf("[%s] query has result set: result_rows=%d", logPrefix, len(getQueryResultsOutput.Results))
+	logger.InfoContext(ctx, "query completed", append(
+		statisticsLogAttrs(getQueryResultsOutput.Status, getQueryResultsOutput.Statistics, time.Since(queryStart)),
+		slog.Int("result_rows", len(getQueryResultsOutput.Results)),
+	)...)
 	return getQueryResultsOutput, nil
 }
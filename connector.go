@@ -3,19 +3,184 @@ package cloudwatchlogsinsightsdriver
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 type cloudwatchLogsInsightsConnector struct {
-	d   *cloudwatchLogsInsightsDriver
-	cfg *CloudwatchLogsInsightsConfig
+	d             *cloudwatchLogsInsightsDriver
+	cfg           *CloudwatchLogsInsightsConfig
+	logGroupCache *logGroupCache
+	limiter       *queryLimiter
+	pollLimiter   *tokenBucket
+
+	awsConfig           *aws.Config
+	client              CloudwatchLogsClient
+	progressHook        func(QueryProgress)
+	statisticsCollector func(QueryStatistics)
+
+	clientMu        sync.Mutex
+	cachedClient    CloudwatchLogsClient
+	clientCreatedAt time.Time
+
+	telemetryOnce sync.Once
+	telemetry     *telemetry
+	telemetryErr  error
+}
+
+// ConnectorOption is the option of NewConnector.
+type ConnectorOption func(*cloudwatchLogsInsightsConnector)
+
+// WithAWSConfig sets the AWS config to construct the client, instead of loading the default config.
+// The region, the profile and the SDK retry settings of CloudwatchLogsInsightsConfig are ignored,
+// and the role and the endpoint are applied.
+func WithAWSConfig(awsCfg aws.Config) ConnectorOption {
+	return func(c *cloudwatchLogsInsightsConnector) {
+		c.awsConfig = &awsCfg
+	}
+}
+
+// WithClient sets the client used by all connections of the connector.
+func WithClient(client CloudwatchLogsClient) ConnectorOption {
+	return func(c *cloudwatchLogsInsightsConnector) {
+		c.client = client
+	}
+}
+
+// WithLogger sets the structured logger of the connector, same as CloudwatchLogsInsightsConfig.Logger.
+func WithLogger(logger *slog.Logger) ConnectorOption {
+	return func(c *cloudwatchLogsInsightsConnector) {
+		c.cfg.Logger = logger
+	}
+}
+
+// WithProgressHook sets the hook called on every poll of all queries of the connector.
+// The hooks of WithQueryProgressHook are called after it.
+func WithProgressHook(hook func(QueryProgress)) ConnectorOption {
+	return func(c *cloudwatchLogsInsightsConnector) {
+		c.progressHook = hook
+	}
+}
+
+// WithStatisticsCollector sets the collector called with the statistics of all queries of the connector.
+// The collectors of WithQueryStatisticsCollector are called after it.
+func WithStatisticsCollector(collector func(QueryStatistics)) ConnectorOption {
+	return func(c *cloudwatchLogsInsightsConnector) {
+		c.statisticsCollector = collector
+	}
+}
+
+// NewConnector returns the connector of the config for sql.OpenDB.
+// The zero values of the config are replaced by the defaults of ParseDSN, and cfg is not modified.
+// The config is validated same as a DSN.
+// Without WithClient and WithAWSConfig, the client is constructed from the config same as a DSN.
+func NewConnector(cfg *CloudwatchLogsInsightsConfig, opts ...ConnectorOption) (driver.Connector, error) {
+	if cfg == nil {
+		cfg = &CloudwatchLogsInsightsConfig{}
+	}
+	copied := *cfg
+	copied.setDefaults()
+	if err := copied.validate(); err != nil {
+		return nil, err
+	}
+	c := newConnector(&cloudwatchLogsInsightsDriver{}, &copied)
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.client != nil && c.awsConfig != nil {
+		return nil, errors.New("can not set WithClient and WithAWSConfig at the same time")
+	}
+	return c, nil
+}
+
+func newConnector(d *cloudwatchLogsInsightsDriver, cfg *CloudwatchLogsInsightsConfig) *cloudwatchLogsInsightsConnector {
+	return &cloudwatchLogsInsightsConnector{
+		d:             d,
+		cfg:           cfg,
+		logGroupCache: newLogGroupCache(cfg.LogGroupCacheTTL),
+		limiter:       newQueryLimiter(cfg.MaxConcurrentQueries),
+		pollLimiter:   newTokenBucket(cfg.GetQueryResultsRate, cfg.GetQueryResultsBurst),
+	}
 }
 
 func (c *cloudwatchLogsInsightsConnector) Connect(ctx context.Context) (driver.Conn, error) {
-	client, err := newCloudwatchLogsClientClient(ctx, c.cfg)
+	client, err := c.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.telemetryOnce.Do(func() {
+		c.telemetry, c.telemetryErr = newTelemetry(c.cfg)
+	})
+	if c.telemetryErr != nil {
+		return nil, fmt.Errorf("telemetry:%w", c.telemetryErr)
+	}
+	return newConn(client, c), nil
+}
+
+// newClient returns the client shared by the connections of the connector.
+// The client is constructed on the first connection, and reconstructed after ClientRefreshInterval if it is set.
+// If the reconstruction fails, the previous client is used until the next connection.
+func (c *cloudwatchLogsInsightsConnector) newClient(ctx context.Context) (CloudwatchLogsClient, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+	c.clientMu.Lock()
+	defer c.clientMu.Unlock()
+	if c.cachedClient != nil && (c.cfg.ClientRefreshInterval <= 0 || time.Since(c.clientCreatedAt) < c.cfg.ClientRefreshInterval) {
+		return c.cachedClient, nil
+	}
+	var client CloudwatchLogsClient
+	var err error
+	if c.awsConfig != nil {
+		client = newCloudwatchLogsClientFromAWSConfig(*c.awsConfig, c.cfg)
+	} else {
+		client, err = newCloudwatchLogsClientClient(ctx, c.cfg)
+	}
 	if err != nil {
+		if c.cachedClient != nil {
+			c.logger().WarnContext(ctx, "failed to refresh the client, uses the previous client", slog.Any("error", err))
+			return c.cachedClient, nil
+		}
 		return nil, err
 	}
-	return newConn(client, c.cfg), nil
+	c.cachedClient = client
+	c.clientCreatedAt = time.Now()
+	return client, nil
+}
+
+// withHooks returns the context with the hooks of the connector.
+func (c *cloudwatchLogsInsightsConnector) withHooks(ctx context.Context) context.Context {
+	if c.progressHook != nil {
+		ctx = withParentHook(ctx, progressHookKey{}, c.progressHook)
+	}
+	if c.statisticsCollector != nil {
+		ctx = withParentHook(ctx, statisticsCollectorKey{}, c.statisticsCollector)
+	}
+	return ctx
+}
+
+// withParentHook sets hook to be called before the hook of the context.
+func withParentHook[T any](ctx context.Context, key any, hook func(T)) context.Context {
+	if child, ok := ctx.Value(key).(func(T)); ok {
+		parent := hook
+		hook = func(v T) {
+			parent(v)
+			child(v)
+		}
+	}
+	return context.WithValue(ctx, key, hook)
+}
+
+func (c *cloudwatchLogsInsightsConnector) logger() *slog.Logger {
+	if c.cfg.Logger != nil {
+		return c.cfg.Logger
+	}
+	return slog.New(NewLegacyLogHandler())
 }
 
 func (c *cloudwatchLogsInsightsConnector) Driver() driver.Driver {
@@ -0,0 +1,186 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewConnector(t *testing.T) {
+	client := &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			if coalesce(params.LogGroupName) != "test-log-group" {
+				t.Errorf("unexpected log group: %s", coalesce(params.LogGroupName))
+			}
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:     types.QueryStatusComplete,
+				Results:    [][]types.ResultField{resultRow("@message", "hello")},
+				Statistics: &types.QueryStatistics{BytesScanned: 100},
+			}, nil
+		},
+	}
+	var calls []string
+	cfg := &CloudwatchLogsInsightsConfig{LogGroupNames: []string{"test-log-group"}}
+	connector, err := NewConnector(cfg,
+		WithClient(client),
+		WithProgressHook(func(p QueryProgress) { calls = append(calls, "connector progress") }),
+		WithStatisticsCollector(func(s QueryStatistics) { calls = append(calls, "connector statistics") }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Timeout != 0 {
+		t.Error("cfg is modified:", cfg.Timeout)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := WithQueryProgressHook(context.Background(), func(p QueryProgress) { calls = append(calls, "query progress") })
+	ctx = WithQueryStatisticsCollector(ctx, func(s QueryStatistics) { calls = append(calls, "query statistics") })
+	var message string
+	if err := db.QueryRowContext(ctx, "fields @message").Scan(&message); err != nil {
+		t.Fatal(err)
+	}
+	if message != "hello" {
+		t.Error("unexpected message:", message)
+	}
+	expected := []string{"connector progress", "query progress", "connector statistics", "query statistics"}
+	if len(calls) != len(expected) {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+	for i, call := range expected {
+		if calls[i] != call {
+			t.Errorf("unexpected calls: %v", calls)
+		}
+	}
+
+	if _, err := NewConnector(nil, WithClient(client), WithAWSConfig(aws.Config{})); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestNewConnector__LoggerAndTelemetry(t *testing.T) {
+	client := &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+			}, nil
+		},
+	}
+	var buf bytes.Buffer
+	spans := tracetest.NewSpanRecorder()
+	connector, err := NewConnector(&CloudwatchLogsInsightsConfig{
+		LogGroupNames:  []string{"test-log-group"},
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+	}, WithClient(client), WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	rows, err := db.QueryContext(context.Background(), "fields @message")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if !strings.Contains(buf.String(), `"msg":"query completed"`) {
+		t.Error("unexpected log:", buf.String())
+	}
+	if n := len(spans.Ended()); n != 1 {
+		t.Error("unexpected spans:", n)
+	}
+}
+
+func TestNewConnector__LogGroupCacheTTL(t *testing.T) {
+	for _, c := range []struct {
+		ttl   time.Duration
+		calls int
+	}{
+		{ttl: 0, calls: 1},
+		{ttl: NoLogGroupCache, calls: 2},
+	} {
+		t.Run(c.ttl.String(), func(t *testing.T) {
+			client := &mockCloudWatchLogsClient{
+				DescribeLogGroupsFunc: func(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+					return &cloudwatchlogs.DescribeLogGroupsOutput{
+						LogGroups: []types.LogGroup{{LogGroupName: aws.String("/aws/lambda/a")}},
+					}, nil
+				},
+				StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+					return &cloudwatchlogs.StartQueryOutput{
+						QueryId: aws.String("test-query-id"),
+					}, nil
+				},
+				GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+					return &cloudwatchlogs.GetQueryResultsOutput{
+						Status: types.QueryStatusComplete,
+					}, nil
+				},
+			}
+			connector, err := NewConnector(&CloudwatchLogsInsightsConfig{
+				LogGroupPrefix:   "/aws/lambda/",
+				LogGroupCacheTTL: c.ttl,
+			}, WithClient(client))
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := sql.OpenDB(connector)
+			defer db.Close()
+			for i := 0; i < 2; i++ {
+				rows, err := db.QueryContext(context.Background(), "fields @message")
+				if err != nil {
+					t.Fatal(err)
+				}
+				rows.Close()
+			}
+			if client.DescribeLogGroupsCallCount != c.calls {
+				t.Error("unexpected DescribeLogGroups call count:", client.DescribeLogGroupsCallCount)
+			}
+		})
+	}
+}
+
+func TestNewConnector__Error(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  *CloudwatchLogsInsightsConfig
+	}{
+		{"limit", &CloudwatchLogsInsightsConfig{Limit: aws.Int32(maxQueryResults + 1)}},
+		{"shard_error_policy", &CloudwatchLogsInsightsConfig{ShardErrorPolicy: "random"}},
+		{"polling", &CloudwatchLogsInsightsConfig{Polling: -time.Second}},
+		{"split_interval", &CloudwatchLogsInsightsConfig{SplitInterval: time.Millisecond}},
+		{"retry", &CloudwatchLogsInsightsConfig{PollRetry: RetryPolicy{MaxAttempts: -1}}},
+		{"credentials", &CloudwatchLogsInsightsConfig{ExternalID: "external-id"}},
+		{"get_query_results_rate", &CloudwatchLogsInsightsConfig{GetQueryResultsRate: -1}},
+		{"log_group_pattern", &CloudwatchLogsInsightsConfig{LogGroupPrefix: "/aws/lambda/", LogGroupPattern: "billing"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewConnector(c.cfg, WithClient(&mockCloudWatchLogsClient{}))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			t.Log(err)
+		})
+	}
+}
@@ -25,8 +25,5 @@ func (d *cloudwatchLogsInsightsDriver) OpenConnector(dsn string) (driver.Connect
 	if err != nil {
 		return nil, err
 	}
-	return &cloudwatchLogsInsightsConnector{
-		d:   d,
-		cfg: cfg,
-	}, nil
+	return newConnector(d, cfg), nil
 }
@@ -4,14 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
 )
 
 var mockClients = map[string]*mockCloudWatchLogsClient{
@@ -19,12 +24,13 @@ var mockClients = map[string]*mockCloudWatchLogsClient{
 }
 
 func init() {
-	CloudwatchLogsClientConstructor = func(ctx context.Context, cfg *CloudwatchLogsInsightsConfig) (CloudwatchLogsClient, error) {
-		if mockName := cfg.Params.Get("mock"); mockName != "" {
-			return mockClients[mockName], nil
+	RegisterClientConstructor("mock", func(ctx context.Context, cfg *CloudwatchLogsInsightsConfig) (CloudwatchLogsClient, error) {
+		mockClient, ok := mockClients[cfg.Params.Get("mock")]
+		if !ok {
+			return nil, fmt.Errorf("mock client %q is not found", cfg.Params.Get("mock"))
 		}
-		return DefaultCloudwatchLogsClientConstructor(ctx, cfg)
-	}
+		return mockClient, nil
+	})
 	GetDebugLogger().SetOutput(os.Stderr)
 }
 
@@ -118,7 +124,7 @@ func TestQueryRwowContext__WithMock__SuccessCase(t *testing.T) {
 			}, nil
 		},
 	}
-	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?mock=success_case&log_group_name=test-log-group")
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=success_case&log_group_name=test-log-group")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -204,7 +210,7 @@ func TestQueryRowContext__WithMock__SuccessCaseWithNamedParameter(t *testing.T)
 			}, nil
 		},
 	}
-	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?mock=success_case_with_named_parameter&log_group_name=ignored")
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=success_case_with_named_parameter&log_group_name=ignored")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -240,7 +246,7 @@ func TestQueryRowContext__WithMock__SuccessCaseWithNamedParameter(t *testing.T)
 }
 
 func TestQueryContext__WITHMock__LogGroupNameIsRequired(t *testing.T) {
-	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?mock=none")
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=none")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -261,7 +267,7 @@ func TestQueryContext__WITHMock__StartQueryError(t *testing.T) {
 			return nil, errors.New("start query error")
 		},
 	}
-	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?mock=start_query_error&log_group_name=test-log-group")
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=start_query_error&log_group_name=test-log-group")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -298,7 +304,7 @@ func TestQueryContext__WITHMock__GetQueryResultsError(t *testing.T) {
 			}, nil
 		},
 	}
-	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?mock=get_query_results_error")
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=get_query_results_error")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -345,7 +351,7 @@ func TestQueryRowContext__WITHMock__Timeout(t *testing.T) {
 			}, nil
 		},
 	}
-	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?mock=timeout&timeout=1ms")
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=timeout&timeout=1ms")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -384,7 +390,7 @@ func TestQueryRowContext__WITHMock__QueryFailed(t *testing.T) {
 			}, nil
 		},
 	}
-	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?mock=query_failed")
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=query_failed")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -414,7 +420,7 @@ func TestQueryRowContext__WITHMock__QueryFailed(t *testing.T) {
 }
 
 func TestExecContext__Unsupported(t *testing.T) {
-	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?mock=none")
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=none")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -428,3 +434,1275 @@ func TestExecContext__Unsupported(t *testing.T) {
 		t.Fatal("unexpected error:", err)
 	}
 }
+
+func TestPrepareContext__WithMock__BindParameters(t *testing.T) {
+	mockClients["bind_parameters"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			expected := `fields @timestamp, @message | filter user = "a\" | display \"" and status >= 500 | limit 1`
+			if coalesce(params.QueryString) != expected {
+				t.Fatalf("unexpected query string: %s", coalesce(params.QueryString))
+			}
+			if coalesce(params.Limit) != 10 {
+				t.Fatal("unexpected limit:", coalesce(params.Limit))
+			}
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=bind_parameters&log_group_name=test-log-group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	stmt, err := db.PrepareContext(ctx, "fields @timestamp, @message | filter user = ? and status >= :status | limit 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx,
+		`a" | display "`,
+		sql.Named("status", 500),
+		sql.Named("limit", 10),
+		sql.Named("start_time", "2020-01-01T00:00:00+09:00"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		t.Fatal("unexpected row")
+	}
+	if mockClients["bind_parameters"].StartQueryCallCount != 1 {
+		t.Fatal("unexpected StartQuery call count:", mockClients["bind_parameters"].StartQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__ColumnTypes(t *testing.T) {
+	mockClients["column_types"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			row := func(bin, path, count, avg, ok string) []types.ResultField {
+				return []types.ResultField{
+					{Field: aws.String("bin(5m)"), Value: aws.String(bin)},
+					{Field: aws.String("path"), Value: aws.String(path)},
+					{Field: aws.String("count(*)"), Value: aws.String(count)},
+					{Field: aws.String("avg(duration)"), Value: aws.String(avg)},
+					{Field: aws.String("ok"), Value: aws.String(ok)},
+				}
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+				Results: [][]types.ResultField{
+					row("2020-01-01 00:00:00.000", "007", "10", "1.5", "true"),
+					row("2020-01-01 00:05:00.000", "/api", "3", "2", "false"),
+				},
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=column_types&log_group_name=test-log-group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "stats count(*), avg(duration) by bin(5m), path, ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"TIMESTAMP", "STRING", "INTEGER", "FLOAT", "BOOLEAN"}
+	for i, ct := range columnTypes {
+		if ct.DatabaseTypeName() != expected[i] {
+			t.Errorf("column %s: expected %s, got %s", ct.Name(), expected[i], ct.DatabaseTypeName())
+		}
+		if nullable, ok := ct.Nullable(); !nullable || !ok {
+			t.Errorf("column %s: expected nullable", ct.Name())
+		}
+	}
+	if !rows.Next() {
+		t.Fatal("expected row")
+	}
+	var (
+		bin   time.Time
+		path  string
+		count int64
+		avg   float64
+		ok    bool
+	)
+	if err := rows.Scan(&bin, &path, &count, &avg, &ok); err != nil {
+		t.Fatal(err)
+	}
+	if bin.Unix() != 1577836800 || path != "007" || count != 10 || avg != 1.5 || !ok {
+		t.Fatal("unexpected values:", bin, path, count, avg, ok)
+	}
+}
+
+func TestQueryContext__WithMock__SplitInterval(t *testing.T) {
+	// one record per second, newest first, at most 10000 records per query
+	mockClients["split_interval"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			if coalesce(params.Limit) != 10000 {
+				t.Error("unexpected limit:", coalesce(params.Limit))
+			}
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String(fmt.Sprintf("%d-%d", *params.StartTime, *params.EndTime)),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			var start, end int64
+			fmt.Sscanf(*params.QueryId, "%d-%d", &start, &end)
+			var results [][]types.ResultField
+			for ts := end; ts >= start && len(results) < 10000; ts-- {
+				results = append(results, resultRow(
+					"@timestamp", time.Unix(ts, 0).UTC().Format("2006-01-02 15:04:05.000"),
+					"@ptr", strconv.FormatInt(ts, 10),
+				))
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  types.QueryStatusComplete,
+				Results: results,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=split_interval&log_group_name=test-log-group&split_interval=6h&concurrency=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "fields @timestamp",
+		sql.Named("start_time", "2020-01-01T00:00:00Z"),
+		sql.Named("end_time", "2020-01-02T00:00:00Z"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	expected := int64(1577923200)
+	for rows.Next() {
+		var ts time.Time
+		if err := rows.Scan(&ts); err != nil {
+			t.Fatal(err)
+		}
+		if ts.Unix() != expected {
+			t.Fatalf("unexpected timestamp: expected %d, got %d", expected, ts.Unix())
+		}
+		expected--
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if expected != 1577836800-1 {
+		t.Fatal("unexpected last timestamp:", expected+1)
+	}
+}
+
+func TestQueryContext__WithMock__SplitInterval__Truncated(t *testing.T) {
+	mockClients["split_interval_truncated"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			results := make([][]types.ResultField, 10000)
+			for i := range results {
+				results[i] = resultRow("@message", "hello", "@ptr", strconv.Itoa(i))
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  types.QueryStatusComplete,
+				Results: results,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=split_interval_truncated&log_group_name=test-log-group&split_interval=1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.QueryContext(context.Background(), "fields @message",
+		sql.Named("start_time", "2020-01-01T00:00:00Z"),
+		sql.Named("end_time", "2020-01-01T00:00:02Z"),
+	)
+	if !errors.Is(err, ErrResultsTruncated) {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestQueryContext__WithMock__SplitInterval__SortLimit(t *testing.T) {
+	mockClients["split_interval_sort_limit"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String(strconv.FormatInt(aws.ToInt64(params.StartTime), 10)),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			// each window returns its own top 3.
+			results := [][]types.ResultField{
+				resultRow("duration", "30", "@ptr", "a"),
+				resultRow("duration", "20", "@ptr", "b"),
+				resultRow("duration", "10", "@ptr", "c"),
+			}
+			if *params.QueryId != strconv.FormatInt(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), 10) {
+				results = [][]types.ResultField{
+					resultRow("duration", "25", "@ptr", "d"),
+					resultRow("duration", "15", "@ptr", "e"),
+					resultRow("duration", "5", "@ptr", "f"),
+				}
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  types.QueryStatusComplete,
+				Results: results,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=split_interval_sort_limit&log_group_name=test-log-group&split_interval=1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.QueryContext(context.Background(), "fields duration | sort duration desc | limit 3",
+		sql.Named("start_time", "2020-01-01T00:00:00Z"),
+		sql.Named("end_time", "2020-01-01T02:00:00Z"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var durations []int64
+	for rows.Next() {
+		var d int64
+		if err := rows.Scan(&d); err != nil {
+			t.Fatal(err)
+		}
+		durations = append(durations, d)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(durations, []int64{30, 25, 20}) {
+		t.Fatal("unexpected durations:", durations)
+	}
+}
+
+func TestQueryContext__WithMock__SplitInterval__Stats(t *testing.T) {
+	mockClients["split_interval_stats"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			if expected := "stats count(*) as __agg0 by path"; coalesce(params.QueryString) != expected {
+				t.Errorf("unexpected query string: %s", coalesce(params.QueryString))
+			}
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  types.QueryStatusComplete,
+				Results: [][]types.ResultField{resultRow("path", "/a", "__agg0", "2")},
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=split_interval_stats&log_group_name=test-log-group&split_interval=12h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var path string
+	var count int64
+	err = db.QueryRowContext(context.Background(), "stats count(*) by path",
+		sql.Named("start_time", "2020-01-01T00:00:00Z"),
+		sql.Named("end_time", "2020-01-02T00:00:00Z"),
+	).Scan(&path, &count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/a" || count != 4 {
+		t.Errorf("unexpected result: %s %d", path, count)
+	}
+	if mockClients["split_interval_stats"].StartQueryCallCount != 2 {
+		t.Fatal("unexpected StartQuery call count:", mockClients["split_interval_stats"].StartQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__PartitionedStats(t *testing.T) {
+	var mu sync.Mutex
+	windows := map[int64]int64{}
+	mockClients["partitioned_stats"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			expected := "filter status >= 500\n| stats count(*) as __agg0, sum(duration) as __agg1_sum, count(duration) as __agg1_count by path"
+			if coalesce(params.QueryString) != expected {
+				t.Errorf("unexpected query string: %s", coalesce(params.QueryString))
+			}
+			if params.LogGroupName == nil {
+				t.Error("expected one log group per partition")
+			}
+			mu.Lock()
+			windows[*params.StartTime] = *params.EndTime
+			mu.Unlock()
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: params.LogGroupName,
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			results := [][]types.ResultField{
+				resultRow("path", "/a", "__agg0", "2", "__agg1_sum", "10", "__agg1_count", "2"),
+			}
+			if *params.QueryId == "test-log-group-2" {
+				results = append(results, resultRow("path", "/b", "__agg0", "3", "__agg1_sum", "3", "__agg1_count", "3"))
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  types.QueryStatusComplete,
+				Results: results,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=partitioned_stats&log_group_names=test-log-group,test-log-group-2&partition_interval=12h&partition_log_groups=1&concurrency=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "filter status >= 500 | stats count(*) as errors, avg(duration) by path | sort errors desc",
+		sql.Named("start_time", "2020-01-01T00:00:00Z"),
+		sql.Named("end_time", "2020-01-02T00:00:00Z"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	type result struct {
+		path   string
+		errors int64
+		avg    float64
+	}
+	var actual []result
+	for rows.Next() {
+		var r result
+		if err := rows.Scan(&r.path, &r.errors, &r.avg); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, r)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []result{{"/a", 8, 5}, {"/b", 6, 1}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	expectedWindows := map[int64]int64{1577836800: 1577880000 - 1, 1577880000: 1577923200}
+	if !reflect.DeepEqual(windows, expectedWindows) {
+		t.Errorf("expected windows %v, got %v", expectedWindows, windows)
+	}
+	if mockClients["partitioned_stats"].StartQueryCallCount != 4 {
+		t.Fatal("unexpected StartQuery call count:", mockClients["partitioned_stats"].StartQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__PartitionedStats__Truncated(t *testing.T) {
+	mockClients["partitioned_stats_truncated"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			results := make([][]types.ResultField, 10000)
+			for i := range results {
+				results[i] = resultRow("path", strconv.Itoa(i), "__agg0", "1")
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  types.QueryStatusComplete,
+				Results: results,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=partitioned_stats_truncated&log_group_name=test-log-group&partition_interval=12h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.QueryContext(context.Background(), "stats count(*) by path",
+		sql.Named("start_time", "2020-01-01T00:00:00Z"),
+		sql.Named("end_time", "2020-01-02T00:00:00Z"),
+	)
+	if !errors.Is(err, ErrResultsTruncated) {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestQueryContext__WithMock__FanOut(t *testing.T) {
+	logGroupNames := make([]string, 120)
+	for i := range logGroupNames {
+		logGroupNames[i] = fmt.Sprintf("/aws/lambda/func-%03d", i)
+	}
+	mockClients["fan_out"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			if len(params.LogGroupNames) > 50 {
+				t.Error("unexpected log group names length:", len(params.LogGroupNames))
+			}
+			if params.LogGroupNames[0] == "/aws/lambda/func-100" {
+				return nil, errors.New("shard error")
+			}
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String(params.LogGroupNames[0]),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			var results [][]types.ResultField
+			switch *params.QueryId {
+			case "/aws/lambda/func-000":
+				results = [][]types.ResultField{
+					resultRow("duration", "30", "@ptr", "a"),
+					resultRow("duration", "10", "@ptr", "b"),
+				}
+			case "/aws/lambda/func-050":
+				results = [][]types.ResultField{
+					resultRow("duration", "20", "@ptr", "c"),
+					resultRow("duration", "5", "@ptr", "d"),
+				}
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  types.QueryStatusComplete,
+				Results: results,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=fan_out&log_group_names="+strings.Join(logGroupNames, ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	query := "fields duration | sort duration desc | limit 3"
+	_, err = db.QueryContext(ctx, query)
+	if err == nil || !strings.Contains(err.Error(), "shard error") {
+		t.Fatal("unexpected error:", err)
+	}
+
+	rows, err := db.QueryContext(ctx, query, sql.Named("shard_error_policy", "partial"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var durations []int64
+	for rows.Next() {
+		var d int64
+		if err := rows.Scan(&d); err != nil {
+			t.Fatal(err)
+		}
+		durations = append(durations, d)
+	}
+	if !reflect.DeepEqual(durations, []int64{30, 20, 10}) {
+		t.Fatal("unexpected durations:", durations)
+	}
+	err = rows.Err()
+	if !errors.Is(err, ErrPartialResults) {
+		t.Fatal("unexpected error:", err)
+	}
+	t.Log(err)
+	var partial *PartialResultsError
+	if !errors.As(err, &partial) || partial.Shards != 3 || len(partial.ShardErrors) != 1 || partial.ShardErrors[0].Shard != 3 || partial.ShardErrors[0].LogGroups[0] != "/aws/lambda/func-100" {
+		t.Fatal("unexpected partial results error:", err)
+	}
+}
+
+func TestQueryContext__WithMock__LogGroupPrefix(t *testing.T) {
+	mockClients["log_group_prefix"] = &mockCloudWatchLogsClient{
+		DescribeLogGroupsFunc: func(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+			if coalesce(params.LogGroupNamePrefix) != "/aws/lambda/billing-" {
+				t.Fatal("unexpected log group name prefix:", coalesce(params.LogGroupNamePrefix))
+			}
+			if params.NextToken == nil {
+				return &cloudwatchlogs.DescribeLogGroupsOutput{
+					LogGroups: []types.LogGroup{{LogGroupName: aws.String("/aws/lambda/billing-a")}},
+					NextToken: aws.String("next"),
+				}, nil
+			}
+			return &cloudwatchlogs.DescribeLogGroupsOutput{
+				LogGroups: []types.LogGroup{{LogGroupName: aws.String("/aws/lambda/billing-b")}},
+			}, nil
+		},
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			if !reflect.DeepEqual(params.LogGroupNames, []string{"/aws/lambda/billing-a", "/aws/lambda/billing-b"}) {
+				t.Fatal("unexpected log group names:", params.LogGroupNames)
+			}
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=log_group_prefix&log_group_prefix=/aws/lambda/billing-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		rows, err := db.QueryContext(ctx, "fields @timestamp, @message")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+	}
+	// two pages for the first query, and the second query uses the cache.
+	if mockClients["log_group_prefix"].DescribeLogGroupsCallCount != 2 {
+		t.Fatal("unexpected DescribeLogGroups call count:", mockClients["log_group_prefix"].DescribeLogGroupsCallCount)
+	}
+	if mockClients["log_group_prefix"].StartQueryCallCount != 2 {
+		t.Fatal("unexpected StartQuery call count:", mockClients["log_group_prefix"].StartQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__LogGroupIdentifiers(t *testing.T) {
+	mockClients["log_group_identifiers"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			expected := []string{
+				"arn:aws:logs:ap-northeast-1:123456789012:log-group:/aws/lambda/hoge",
+				"arn:aws:logs:ap-northeast-1:210987654321:log-group:/aws/lambda/bar",
+			}
+			if !reflect.DeepEqual(params.LogGroupIdentifiers, expected) {
+				t.Fatal("unexpected log group identifiers:", params.LogGroupIdentifiers)
+			}
+			if params.LogGroupName != nil || params.LogGroupNames != nil {
+				t.Fatal("unexpected log group names")
+			}
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=log_group_identifiers&region=ap-northeast-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "fields @timestamp, @message",
+		sql.Named("log_group_name", "123456789012:/aws/lambda/hoge"),
+		sql.Named("log_group_name", "arn:aws:logs:ap-northeast-1:210987654321:log-group:/aws/lambda/bar:*"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	_, err = db.QueryContext(ctx, "fields @timestamp, @message",
+		sql.Named("log_group_name", "123456789012:/aws/lambda/hoge"),
+		sql.Named("log_group_name", "/aws/lambda/bar"),
+	)
+	if err == nil || !strings.Contains(err.Error(), "can not mix") {
+		t.Fatal("unexpected error:", err)
+	}
+	if mockClients["log_group_identifiers"].StartQueryCallCount != 1 {
+		t.Fatal("unexpected StartQuery call count:", mockClients["log_group_identifiers"].StartQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__QueryStatistics(t *testing.T) {
+	mockClients["query_statistics"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("query-" + strconv.FormatInt(aws.ToInt64(params.StartTime), 10)),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+				Statistics: &types.QueryStatistics{
+					RecordsMatched: 10,
+					RecordsScanned: 100,
+					BytesScanned:   1000,
+				},
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=query_statistics&log_group_name=test-log-group&split_interval=1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var mu sync.Mutex
+	var collected []QueryStatistics
+	ctx := WithQueryStatisticsCollector(context.Background(), func(s QueryStatistics) {
+		mu.Lock()
+		defer mu.Unlock()
+		collected = append(collected, s)
+	})
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rows, err := conn.QueryContext(ctx, "fields @timestamp, @message",
+		sql.Named("start_time", "2020-01-01T00:00:00Z"),
+		sql.Named("end_time", "2020-01-01T02:00:00Z"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if len(collected) != 2 {
+		t.Fatal("unexpected collected statistics:", collected)
+	}
+	err = conn.Raw(func(driverConn any) error {
+		stats := driverConn.(QueryStatisticsProvider).QueryStatistics()
+		if len(stats) != 2 {
+			t.Fatal("unexpected statistics:", stats)
+		}
+		if stats[0].QueryID == "" || stats[0].QueryID == stats[1].QueryID {
+			t.Fatal("unexpected query ids:", stats)
+		}
+		total := TotalQueryStatistics(stats)
+		if total.RecordsMatched != 20 || total.RecordsScanned != 200 || total.BytesScanned != 2000 {
+			t.Fatal("unexpected total statistics:", total)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryContext__WithMock__QueryStatistics__NotCompleted(t *testing.T) {
+	mockClients["query_statistics_not_completed"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: params.QueryString,
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			status := types.QueryStatusRunning
+			if *params.QueryId == "failed" {
+				status = types.QueryStatusFailed
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: status,
+				Statistics: &types.QueryStatistics{
+					RecordsScanned: 100,
+				},
+			}, nil
+		},
+		StopQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+			return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=query_statistics_not_completed&log_group_name=test-log-group&polling=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{"failed", "timeout", "stream"} {
+		t.Run(query, func(t *testing.T) {
+			var mu sync.Mutex
+			var collected []QueryStatistics
+			ctx := WithQueryStatisticsCollector(context.Background(), func(s QueryStatistics) {
+				mu.Lock()
+				defer mu.Unlock()
+				collected = append(collected, s)
+			})
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			if query == "stream" {
+				rows, err := db.QueryContext(ctx, query, sql.Named("stream", true))
+				if err == nil {
+					for rows.Next() {
+					}
+					err = rows.Err()
+					rows.Close()
+				}
+				t.Log(err)
+			} else if _, err := db.QueryContext(ctx, query); err == nil {
+				t.Fatal("unexpected nil error")
+			} else {
+				t.Log(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(collected) != 1 || collected[0].QueryID != query || collected[0].RecordsScanned != 100 {
+				t.Fatal("unexpected collected statistics:", collected)
+			}
+		})
+	}
+}
+
+func TestQueryContext__WithMock__ScanBudgetExceeded(t *testing.T) {
+	var scanned float64
+	mockClients["scan_budget"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			scanned = 0
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			scanned += 600
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusRunning,
+				Results: [][]types.ResultField{
+					resultRow("@message", "partial"),
+				},
+				Statistics: &types.QueryStatistics{
+					BytesScanned:   scanned,
+					RecordsScanned: scanned / 100,
+				},
+			}, nil
+		},
+		StopQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+			return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=scan_budget&log_group_name=test-log-group&max_bytes_scanned=1000&polling=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, err = db.QueryContext(ctx, "fields @message")
+	var budgetErr *ScanBudgetExceededError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrScanBudgetExceeded) {
+		t.Fatal("unexpected error:", err)
+	}
+	if budgetErr.Budget != "max_bytes_scanned" || budgetErr.Scanned != 1200 {
+		t.Fatal("unexpected budget error:", budgetErr)
+	}
+	if mockClients["scan_budget"].StopQueryCallCount != 1 {
+		t.Fatal("unexpected StopQuery call count:", mockClients["scan_budget"].StopQueryCallCount)
+	}
+
+	rows, err := db.QueryContext(ctx, "fields @message",
+		sql.Named("max_bytes_scanned", 0),
+		sql.Named("max_records_scanned", 20),
+		sql.Named("scan_budget_policy", "partial"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var messages []string
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, message)
+	}
+	if !reflect.DeepEqual(messages, []string{"partial"}) {
+		t.Fatal("unexpected messages:", messages)
+	}
+	if mockClients["scan_budget"].StopQueryCallCount != 2 {
+		t.Fatal("unexpected StopQuery call count:", mockClients["scan_budget"].StopQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__StartAndAttachQuery(t *testing.T) {
+	mockClients["attach_query"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			if aws.ToString(params.QueryId) != "test-query-id" {
+				t.Fatal("unexpected query id:", aws.ToString(params.QueryId))
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+				Results: [][]types.ResultField{
+					resultRow("@message", "attached"),
+				},
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=attach_query&log_group_name=test-log-group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	queryID, err := StartQuery(ctx, db, "fields @message")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queryID != "test-query-id" {
+		t.Fatal("unexpected query id:", queryID)
+	}
+	if mockClients["attach_query"].GetQueryResultsCallCount != 0 {
+		t.Fatal("unexpected GetQueryResults call count:", mockClients["attach_query"].GetQueryResultsCallCount)
+	}
+
+	rows, err := AttachQuery(ctx, db, queryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var messages []string
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, message)
+	}
+	if !reflect.DeepEqual(messages, []string{"attached"}) {
+		t.Fatal("unexpected messages:", messages)
+	}
+	if mockClients["attach_query"].StartQueryCallCount != 1 {
+		t.Fatal("unexpected StartQuery call count:", mockClients["attach_query"].StartQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__TimeoutNamedParameter(t *testing.T) {
+	var started time.Time
+	mockClients["timeout_named_parameter"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			started = time.Now()
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			status := types.QueryStatusRunning
+			if time.Since(started) > 100*time.Millisecond {
+				status = types.QueryStatusComplete
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: status,
+			}, nil
+		},
+		StopQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+			return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=timeout_named_parameter&log_group_name=test-log-group&timeout=50ms&polling=10ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.QueryContext(ctx, "fields @message"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("unexpected error:", err)
+	}
+	rows, err := db.QueryContext(ctx, "fields @message", sql.Named("timeout", "1s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+}
+
+func TestQueryContext__WithMock__AttachQuery__Timeout(t *testing.T) {
+	client := &mockCloudWatchLogsClient{
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusRunning,
+			}, nil
+		},
+		StopQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+			return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+		},
+	}
+	mockClients["attach_query_timeout"] = client
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=attach_query_timeout&polling=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, err = AttachQuery(ctx, db, "test-query-id", sql.Named("timeout", "10ms"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("unexpected error:", err)
+	}
+	if client.StopQueryCallCount != 0 {
+		t.Fatal("unexpected StopQuery call count:", client.StopQueryCallCount)
+	}
+	_, err = AttachQuery(ctx, db, "test-query-id", sql.Named("timeout", "10ms"), sql.Named("stop_on_abort", true))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("unexpected error:", err)
+	}
+	if client.StopQueryCallCount != 1 {
+		t.Fatal("unexpected StopQuery call count:", client.StopQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__AttachQuery__ScanBudgetExceeded(t *testing.T) {
+	client := &mockCloudWatchLogsClient{
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusRunning,
+				Statistics: &types.QueryStatistics{
+					BytesScanned: 1200,
+				},
+			}, nil
+		},
+		StopQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+			return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+		},
+	}
+	mockClients["attach_query_scan_budget"] = client
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=attach_query_scan_budget&polling=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, err = AttachQuery(ctx, db, "test-query-id", sql.Named("max_bytes_scanned", 1000))
+	if !errors.Is(err, ErrScanBudgetExceeded) {
+		t.Fatal("unexpected error:", err)
+	}
+	if client.StopQueryCallCount != 1 {
+		t.Fatal("unexpected StopQuery call count:", client.StopQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__TerminalStatuses(t *testing.T) {
+	cases := []struct {
+		status   types.QueryStatus
+		expected error
+	}{
+		{types.QueryStatusFailed, ErrQueryFailed},
+		{types.QueryStatusCancelled, ErrQueryCancelled},
+		{types.QueryStatusTimeout, ErrQueryTimedOut},
+		{types.QueryStatusUnknown, ErrQueryFailed},
+	}
+	for _, c := range cases {
+		t.Run(string(c.status), func(t *testing.T) {
+			mockName := "terminal_status_" + string(c.status)
+			mockClients[mockName] = &mockCloudWatchLogsClient{
+				StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+					return &cloudwatchlogs.StartQueryOutput{
+						QueryId: aws.String("test-query-id"),
+					}, nil
+				},
+				GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+					if mockClients[mockName].GetQueryResultsCallCount == 1 {
+						return &cloudwatchlogs.GetQueryResultsOutput{
+							Status: types.QueryStatusScheduled,
+						}, nil
+					}
+					return &cloudwatchlogs.GetQueryResultsOutput{
+						Status: c.status,
+					}, nil
+				},
+			}
+			db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock="+mockName+"&log_group_name=test-log-group&polling=1ms")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			_, err = db.QueryContext(context.Background(), "fields @message")
+			if !errors.Is(err, c.expected) {
+				t.Fatal("unexpected error:", err)
+			}
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatal("unexpected error type:", err)
+			}
+			if queryErr.QueryID != "test-query-id" || queryErr.Status != c.status || queryErr.Query != "fields @message" {
+				t.Fatal("unexpected query error:", queryErr)
+			}
+			if !reflect.DeepEqual(queryErr.LogGroups, []string{"test-log-group"}) {
+				t.Fatal("unexpected log groups:", queryErr.LogGroups)
+			}
+			if mockClients[mockName].GetQueryResultsCallCount != 2 {
+				t.Fatal("unexpected GetQueryResults call count:", mockClients[mockName].GetQueryResultsCallCount)
+			}
+		})
+	}
+}
+
+func TestQueryContext__WithMock__RetryThrottling(t *testing.T) {
+	mockClients["retry_throttling"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			if mockClients["retry_throttling"].StartQueryCallCount <= 2 {
+				return nil, &smithy.GenericAPIError{Code: "LimitExceededException", Message: "too many concurrent queries"}
+			}
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			if mockClients["retry_throttling"].GetQueryResultsCallCount == 1 {
+				return nil, &smithy.GenericAPIError{Code: "ThrottlingException", Message: "rate exceeded"}
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=retry_throttling&log_group_name=test-log-group&start_retry_base_delay=1ms&poll_retry_base_delay=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "fields @message")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if mockClients["retry_throttling"].StartQueryCallCount != 3 {
+		t.Fatal("unexpected StartQuery call count:", mockClients["retry_throttling"].StartQueryCallCount)
+	}
+	if mockClients["retry_throttling"].GetQueryResultsCallCount != 2 {
+		t.Fatal("unexpected GetQueryResults call count:", mockClients["retry_throttling"].GetQueryResultsCallCount)
+	}
+
+	db2, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=retry_throttling&log_group_name=test-log-group&start_max_attempts=2&start_retry_base_delay=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+	mockClients["retry_throttling"].StartQueryCallCount = 0
+	_, err = db2.QueryContext(ctx, "fields @message")
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "LimitExceededException" {
+		t.Fatal("unexpected error:", err)
+	}
+	if mockClients["retry_throttling"].StartQueryCallCount != 2 {
+		t.Fatal("unexpected StartQuery call count:", mockClients["retry_throttling"].StartQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__StopQueryAfterTimeout(t *testing.T) {
+	mockClients["stop_after_timeout"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusRunning,
+			}, nil
+		},
+		StopQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+			if err := ctx.Err(); err != nil {
+				t.Error("StopQuery with done context:", err)
+			}
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > 2*time.Second {
+				t.Error("unexpected StopQuery deadline:", deadline)
+			}
+			return &cloudwatchlogs.StopQueryOutput{
+				Success: true,
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=stop_after_timeout&log_group_name=test-log-group&stop_timeout=2s&polling=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.QueryContext(context.Background(), "fields @message", sql.Named("timeout", 20*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("unexpected error:", err)
+	}
+	if mockClients["stop_after_timeout"].StopQueryCallCount != 1 {
+		t.Fatal("unexpected StopQuery call count:", mockClients["stop_after_timeout"].StopQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__Stream(t *testing.T) {
+	batches := [][][]types.ResultField{
+		{resultRow("@message", "1", "@ptr", "ptr-1")},
+		{resultRow("@message", "1", "@ptr", "ptr-1"), resultRow("@message", "2", "@ptr", "ptr-2")},
+		{resultRow("@message", "1", "@ptr", "ptr-1"), resultRow("@message", "2", "@ptr", "ptr-2"), resultRow("@message", "3", "@ptr", "ptr-3")},
+	}
+	mockClients["stream"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			n := mockClients["stream"].GetQueryResultsCallCount
+			if n > len(batches) {
+				n = len(batches)
+			}
+			status := types.QueryStatusRunning
+			if n == len(batches) {
+				status = types.QueryStatusComplete
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  status,
+				Results: batches[n-1],
+			}, nil
+		},
+		StopQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+			return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=stream&log_group_name=test-log-group&polling=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "fields @message", sql.Named("stream", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for rows.Next() {
+		if len(messages) == 0 && mockClients["stream"].GetQueryResultsCallCount != 1 {
+			t.Fatal("the first row is not streamed:", mockClients["stream"].GetQueryResultsCallCount)
+		}
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if !reflect.DeepEqual(messages, []string{"1", "2", "3"}) {
+		t.Fatal("unexpected messages:", messages)
+	}
+	if mockClients["stream"].StopQueryCallCount != 0 {
+		t.Fatal("unexpected StopQuery call count:", mockClients["stream"].StopQueryCallCount)
+	}
+
+	// closing the rows stops the running query.
+	mockClients["stream"].GetQueryResultsCallCount = 0
+	rows, err = db.QueryContext(ctx, "fields @message", sql.Named("stream", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() {
+		t.Fatal("unexpected no rows:", rows.Err())
+	}
+	rows.Close()
+	if mockClients["stream"].StopQueryCallCount != 1 {
+		t.Fatal("unexpected StopQuery call count:", mockClients["stream"].StopQueryCallCount)
+	}
+}
+
+func TestQueryContext__WithMock__Stream__Error(t *testing.T) {
+	mockClients["stream_error"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status:  types.QueryStatusRunning,
+				Results: [][]types.ResultField{resultRow("@message", "1", "@ptr", "ptr-1")},
+			}, nil
+		},
+		StopQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+			return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=stream_error&log_group_name=test-log-group&polling=1ms&wait_timeout=20ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	// wait_timeout limits the running query.
+	rows, err := db.QueryContext(ctx, "fields @message", sql.Named("stream", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("unexpected error:", err)
+	}
+	rows.Close()
+
+	cases := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{name: "sort", query: "fields @message | sort @timestamp asc"},
+		{name: "limit", query: "fields @message | limit 10"},
+		{name: "limit named parameter", query: "fields @message", args: []any{sql.Named("limit", int64(10))}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := db.QueryContext(ctx, c.query, append(c.args, sql.Named("stream", true))...)
+			if err == nil {
+				t.Fatal("unexpected nil error")
+			}
+			t.Log(err)
+		})
+	}
+}
+
+func TestQueryContext__WithMock__ProgressHook(t *testing.T) {
+	mockClients["progress_hook"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			n := mockClients["progress_hook"].GetQueryResultsCallCount
+			status := types.QueryStatusRunning
+			if n == 3 {
+				status = types.QueryStatusComplete
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: status,
+				Statistics: &types.QueryStatistics{
+					RecordsScanned: float64(n * 100),
+				},
+			}, nil
+		},
+	}
+	db, err := sql.Open("cloudwatch-logs-insights", "cloudwatch://?client=mock&mock=progress_hook&log_group_name=test-log-group&polling=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var progress []QueryProgress
+	ctx := WithQueryProgressHook(context.Background(), func(p QueryProgress) {
+		progress = append(progress, p)
+	})
+	rows, err := db.QueryContext(ctx, "fields @message")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if len(progress) != 3 {
+		t.Fatal("unexpected progress:", progress)
+	}
+	for i, p := range progress {
+		if p.QueryID != "test-query-id" || p.RecordsScanned != float64((i+1)*100) {
+			t.Error("unexpected progress:", p)
+		}
+	}
+	if progress[1].Status != types.QueryStatusRunning || progress[2].Status != types.QueryStatusComplete {
+		t.Fatal("unexpected status:", progress)
+	}
+}
@@ -1,10 +1,113 @@
 package cloudwatchlogsinsightsdriver
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
 
 var (
 	ErrNotSupported  = errors.New("not supported")
 	ErrDSNEmpty      = errors.New("dsn is empty")
 	ErrConnClosed    = errors.New("connection closed")
 	ErrInvalidScheme = errors.New("invalid scheme")
+	ErrNotMergeable  = errors.New("not mergeable")
+
+	// ErrResultsTruncated is returned when the results of a query can not be split more and hit the 10,000 rows limit.
+	ErrResultsTruncated = errors.New("results are truncated")
+
+	// ErrPartialResults is returned by rows.Err() when some shards failed with shard_error_policy=partial.
+	ErrPartialResults = errors.New("partial results")
+
+	ErrQueryFailed    = errors.New("query failed")
+	ErrQueryCancelled = errors.New("query cancelled")
+	ErrQueryTimedOut  = errors.New("query timed out")
 )
+
+// QueryError is returned when the query is finished with the Failed, Cancelled, Timeout or Unknown status.
+// It matches ErrQueryFailed, ErrQueryCancelled or ErrQueryTimedOut by errors.Is.
+type QueryError struct {
+	QueryID   string
+	Status    types.QueryStatus
+	LogGroups []string
+	Query     string
+}
+
+func newQueryError(queryID string, status types.QueryStatus, params *cloudwatchlogs.StartQueryInput) *QueryError {
+	e := &QueryError{
+		QueryID: queryID,
+		Status:  status,
+	}
+	if params != nil {
+		e.Query = coalesce(params.QueryString)
+		switch {
+		case len(params.LogGroupIdentifiers) > 0:
+			e.LogGroups = params.LogGroupIdentifiers
+		case len(params.LogGroupNames) > 0:
+			e.LogGroups = params.LogGroupNames
+		case params.LogGroupName != nil:
+			e.LogGroups = []string{*params.LogGroupName}
+		}
+	}
+	return e
+}
+
+func (e *QueryError) Error() string {
+	switch e.Status {
+	case types.QueryStatusCancelled:
+		return fmt.Sprintf("%s: %s", ErrQueryCancelled, e.QueryID)
+	case types.QueryStatusTimeout:
+		return fmt.Sprintf("%s: %s", ErrQueryTimedOut, e.QueryID)
+	case types.QueryStatusFailed:
+		return fmt.Sprintf("%s: %s", ErrQueryFailed, e.QueryID)
+	}
+	return fmt.Sprintf("%s: %s: status %s", ErrQueryFailed, e.QueryID, e.Status)
+}
+
+func (e *QueryError) Is(target error) bool {
+	switch e.Status {
+	case types.QueryStatusCancelled:
+		return target == ErrQueryCancelled
+	case types.QueryStatusTimeout:
+		return target == ErrQueryTimedOut
+	}
+	return target == ErrQueryFailed
+}
+
+// ShardError is the error of a shard of the query over more than 50 log groups.
+type ShardError struct {
+	Shard     int // 1-based index of the shard
+	LogGroups []string
+	Err       error
+}
+
+func (e *ShardError) Error() string {
+	return fmt.Sprintf("shard %d failed: %v", e.Shard, e.Err)
+}
+
+func (e *ShardError) Unwrap() error {
+	return e.Err
+}
+
+// PartialResultsError is returned by rows.Err() after the rows of the succeeded shards,
+// when some shards failed with shard_error_policy=partial. It matches ErrPartialResults by errors.Is,
+// and the errors of the failed shards by errors.Is and errors.As.
+type PartialResultsError struct {
+	Shards      int
+	ShardErrors []*ShardError
+}
+
+func (e *PartialResultsError) Error() string {
+	return fmt.Sprintf("partial results: %d of %d shards failed: %v", len(e.ShardErrors), e.Shards, e.ShardErrors[0])
+}
+
+func (e *PartialResultsError) Unwrap() []error {
+	errs := make([]error, 0, len(e.ShardErrors)+1)
+	errs = append(errs, ErrPartialResults)
+	for _, err := range e.ShardErrors {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,98 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// maxLogGroupsPerQuery is the maximum number of log groups that a single Logs Insights query can search.
+const maxLogGroupsPerQuery = 50
+
+// defaultFanOutConcurrency is the number of shards queried in parallel when concurrency is not specified.
+const defaultFanOutConcurrency = 4
+
+// ShardErrorPolicy is the policy for a failed shard of the query over more than 50 log groups.
+type ShardErrorPolicy string
+
+const (
+	// ShardErrorPolicyFailFast cancels the other shards and returns the error. This is the default.
+	ShardErrorPolicyFailFast ShardErrorPolicy = "fail_fast"
+	// ShardErrorPolicyPartial returns the results of the succeeded shards, and PartialResultsError by rows.Err() after them.
+	ShardErrorPolicyPartial ShardErrorPolicy = "partial"
+)
+
+func parseShardErrorPolicy(s string) (ShardErrorPolicy, error) {
+	switch p := ShardErrorPolicy(s); p {
+	case ShardErrorPolicyFailFast, ShardErrorPolicyPartial:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown shard_error_policy %q", s)
+}
+
+// fanOutQuery runs the query for each shard of maxLogGroupsPerQuery log groups,
+// and merges the rows with the sort and limit commands of the query applied client-side.
+// With ShardErrorPolicyPartial, the merged rows of the succeeded shards are returned with *PartialResultsError.
+func (conn *cloudwatchLogsInsightsConn) fanOutQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, opts *queryOptions) ([][]types.ResultField, error) {
+	order, err := parseResultOrder(coalesce(params.QueryString))
+	if err != nil {
+		return nil, err
+	}
+
+	shards := partitionLogGroupNames(opts.logGroupNames, maxLogGroupsPerQuery)
+	concurrency := opts.concurrencyOr(min(len(shards), defaultFanOutConcurrency))
+	conn.logger.DebugContext(ctx, "fan out query", slog.Int("shards", len(shards)), slog.Int("concurrency", concurrency))
+	shardResults := make([][][]types.ResultField, len(shards))
+	shardErrs := make([]error, len(shards))
+	err = runConcurrently(ctx, len(shards), concurrency, func(ctx context.Context, i int) error {
+		shardParams := *params
+		setLogGroups(&shardParams, shards[i])
+		shardResults[i], shardErrs[i] = conn.queryShard(ctx, &shardParams, opts)
+		if shardErrs[i] == nil {
+			return nil
+		}
+		if opts.shardErrorPolicy == ShardErrorPolicyPartial && ctx.Err() == nil {
+			conn.logger.WarnContext(ctx, "shard failed, continue with partial results",
+				slog.Int("shard", i+1), slog.Int("shards", len(shards)), slog.Any(LogKeyLogGroups, shards[i]), slog.Any("error", shardErrs[i]))
+			return nil
+		}
+		return shardErrs[i]
+	})
+	if err != nil {
+		return nil, err
+	}
+	var merged [][]types.ResultField
+	var partial *PartialResultsError
+	for i, results := range shardResults {
+		if shardErrs[i] != nil {
+			if partial == nil {
+				partial = &PartialResultsError{Shards: len(shards)}
+			}
+			partial.ShardErrors = append(partial.ShardErrors, &ShardError{Shard: i + 1, LogGroups: shards[i], Err: shardErrs[i]})
+			continue
+		}
+		merged = append(merged, results...)
+	}
+	if partial != nil && len(partial.ShardErrors) == len(shards) {
+		return nil, fmt.Errorf("all shards failed: %w", shardErrs[0])
+	}
+	merged = order.apply(merged, opts.limit)
+	if partial != nil {
+		return merged, partial
+	}
+	return merged, nil
+}
+
+func (conn *cloudwatchLogsInsightsConn) queryShard(ctx context.Context, params *cloudwatchlogs.StartQueryInput, opts *queryOptions) ([][]types.ResultField, error) {
+	if opts.splitInterval > 0 {
+		return conn.splitQuery(ctx, params, opts)
+	}
+	output, err := conn.startQuery(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return output.Results, nil
+}
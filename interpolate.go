@@ -0,0 +1,332 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Regex is the bind parameter type for regular expressions.
+// The value is interpolated into the query as a regex literal, for example /pattern/.
+//
+//	db.QueryContext(ctx, "fields @message | filter @message like ?", cloudwatchlogsinsightsdriver.Regex("(?i)error"))
+type Regex string
+
+// Field is the bind parameter type for field names.
+// The value is interpolated into the query as a backtick quoted field name, for example `field-name`.
+//
+//	db.QueryContext(ctx, "stats count(*) by :field", sql.Named("field", cloudwatchlogsinsightsdriver.Field("request.path")))
+type Field string
+
+type placeholder struct {
+	start int
+	end   int
+	name  string // empty for ordinal placeholder `?`
+}
+
+// interpolate replaces `?` and `:name` placeholders in the query with the quoted bind parameters.
+// Ordinal parameters are bound to `?` in order, named parameters are bound to `:name`.
+// Named parameters that are not used in the query are ignored, as the driver has always done for unknown names.
+func interpolate(query string, args []driver.NamedValue) (string, error) {
+	if len(args) == 0 {
+		return query, nil
+	}
+	placeholders, err := scanPlaceholders(query)
+	if err != nil {
+		return "", err
+	}
+	var ordinals []driver.NamedValue
+	named := make(map[string]driver.NamedValue, len(args))
+	for _, arg := range args {
+		if arg.Name == "" {
+			ordinals = append(ordinals, arg)
+			continue
+		}
+		named[arg.Name] = arg
+	}
+	var b strings.Builder
+	var pos, ordinal int
+	for _, p := range placeholders {
+		var arg driver.NamedValue
+		if p.name == "" {
+			if ordinal >= len(ordinals) {
+				return "", fmt.Errorf("not enough parameters: placeholder ? at offset %d", p.start)
+			}
+			arg = ordinals[ordinal]
+			ordinal++
+		} else {
+			var ok bool
+			arg, ok = named[p.name]
+			if !ok {
+				return "", fmt.Errorf("named parameter %q is not given", p.name)
+			}
+		}
+		literal, err := formatValue(arg.Value)
+		if err != nil {
+			if p.name != "" {
+				return "", fmt.Errorf("parameter %q: %w", p.name, err)
+			}
+			return "", fmt.Errorf("parameter $%d: %w", arg.Ordinal, err)
+		}
+		b.WriteString(query[pos:p.start])
+		b.WriteString(literal)
+		pos = p.end
+	}
+	b.WriteString(query[pos:])
+	if ordinal < len(ordinals) {
+		return "", fmt.Errorf("too many parameters: expected %d, got %d", ordinal, len(ordinals))
+	}
+	return b.String(), nil
+}
+
+// scanPlaceholders finds placeholders outside of string, regex, field name literals and comments.
+func scanPlaceholders(query string) ([]placeholder, error) {
+	var placeholders []placeholder
+	err := walkQuery(query, func(i int) int {
+		switch query[i] {
+		case '?':
+			placeholders = append(placeholders, placeholder{start: i, end: i + 1})
+		case ':':
+			if i > 0 && (isIdentChar(query[i-1]) || query[i-1] == ':') {
+				return i
+			}
+			j := i + 1
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			if j == i+1 || isDigit(query[i+1]) {
+				return i
+			}
+			placeholders = append(placeholders, placeholder{start: i, end: j, name: query[i+1 : j]})
+			return j - 1
+		}
+		return i
+	})
+	if err != nil {
+		return nil, err
+	}
+	return placeholders, nil
+}
+
+// walkQuery calls fn with the offset of each byte outside of string, regex, field name literals and comments.
+// fn is also called with the offset of `#` that starts a comment.
+// fn returns the offset of the last byte it consumed.
+func walkQuery(query string, fn func(i int) int) error {
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; c {
+		case '"', '\'':
+			end, err := skipQuoted(query, i, c)
+			if err != nil {
+				return err
+			}
+			i = end
+		case '`':
+			end := strings.IndexByte(query[i+1:], '`')
+			if end < 0 {
+				return fmt.Errorf("unterminated field name at offset %d", i)
+			}
+			i += end + 1
+		case '#':
+			if next := fn(i); next != i {
+				i = next
+				continue
+			}
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return nil
+			}
+			i += end
+		case '/':
+			if !regexAllowed(query, i) {
+				i = fn(i)
+				continue
+			}
+			end, err := skipQuoted(query, i, '/')
+			if err != nil {
+				return err
+			}
+			i = end
+		default:
+			i = fn(i)
+		}
+	}
+	return nil
+}
+
+// skipQuoted returns the offset of the closing delimiter of the literal that starts at offset start.
+func skipQuoted(query string, start int, delim byte) (int, error) {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case delim:
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated literal %q at offset %d", string(delim), start)
+}
+
+// regexAllowed reports whether the `/` at offset i starts a regex literal rather than a division.
+func regexAllowed(query string, i int) bool {
+	j := i - 1
+	for j >= 0 && isSpace(query[j]) {
+		j--
+	}
+	if j < 0 {
+		return true
+	}
+	if !isIdentChar(query[j]) && query[j] != ')' && query[j] != '`' {
+		return true
+	}
+	word, j := previousWord(query, j)
+	switch strings.ToLower(word) {
+	case "like", "parse":
+		return true
+	}
+	for j >= 0 && isSpace(query[j]) {
+		j--
+	}
+	if j < 0 {
+		return false
+	}
+	word, _ = previousWord(query, j)
+	return strings.EqualFold(word, "parse")
+}
+
+func previousWord(query string, end int) (string, int) {
+	start := end
+	for start >= 0 && (isIdentChar(query[start]) || query[start] == '@' || query[start] == '.') {
+		start--
+	}
+	return query[start+1 : end+1], start
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func formatValue(v driver.Value) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", errors.New("nil value is not supported")
+	case string:
+		return quoteString(v), nil
+	case []byte:
+		return quoteString(string(v)), nil
+	case Regex:
+		return quoteRegex(string(v))
+	case Field:
+		return quoteField(string(v))
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("%v is not supported", v)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Duration:
+		return formatDuration(v)
+	case time.Time:
+		return strconv.FormatInt(v.UnixMilli(), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func quoteString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func quoteRegex(s string) (string, error) {
+	if s == "" {
+		return "", errors.New("empty regex is not supported")
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('/')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 >= len(s) {
+				return "", errors.New("regex ends with backslash")
+			}
+			b.WriteByte(c)
+			i++
+			b.WriteByte(s[i])
+		case '/':
+			b.WriteString(`\/`)
+		case '\n', '\r':
+			return "", errors.New("regex contains line break")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('/')
+	return b.String(), nil
+}
+
+func quoteField(s string) (string, error) {
+	if s == "" {
+		return "", errors.New("empty field name is not supported")
+	}
+	if strings.ContainsAny(s, "`\n\r") {
+		return "", fmt.Errorf("field name %q contains invalid character", s)
+	}
+	return "`" + s + "`", nil
+}
+
+var durationUnits = []struct {
+	unit string
+	d    time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+}
+
+func formatDuration(d time.Duration) (string, error) {
+	if d <= 0 {
+		return "", fmt.Errorf("duration must be positive: %s", d)
+	}
+	for _, u := range durationUnits {
+		if d%u.d == 0 {
+			return strconv.FormatInt(int64(d/u.d), 10) + u.unit, nil
+		}
+	}
+	return "", fmt.Errorf("duration must be a multiple of millisecond: %s", d)
+}
@@ -0,0 +1,119 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		args     []driver.NamedValue
+		expected string
+	}{
+		{
+			name:     "ordinal string",
+			query:    "fields @message | filter user = ?",
+			args:     []driver.NamedValue{{Ordinal: 1, Value: `a" or 1=1 | display "`}},
+			expected: `fields @message | filter user = "a\" or 1=1 | display \""`,
+		},
+		{
+			name:  "named and ordinal",
+			query: "fields @message | filter status >= :status and path = ? | stats count(*) by bin(:span)",
+			args: []driver.NamedValue{
+				{Ordinal: 1, Value: "/api"},
+				{Name: "status", Ordinal: 2, Value: int64(500)},
+				{Name: "span", Ordinal: 3, Value: 5 * time.Minute},
+			},
+			expected: `fields @message | filter status >= 500 and path = "/api" | stats count(*) by bin(5m)`,
+		},
+		{
+			name:  "unused named parameter",
+			query: "fields @message | filter user = ?",
+			args: []driver.NamedValue{
+				{Ordinal: 1, Value: "a"},
+				{Name: "unknown", Ordinal: 2, Value: "b"},
+			},
+			expected: `fields @message | filter user = "a"`,
+		},
+		{
+			name:     "regex",
+			query:    "fields @message | filter @message like ?",
+			args:     []driver.NamedValue{{Ordinal: 1, Value: Regex(`a/b\/c`)}},
+			expected: `fields @message | filter @message like /a\/b\/c/`,
+		},
+		{
+			name:     "field",
+			query:    "stats count(*) by ?",
+			args:     []driver.NamedValue{{Ordinal: 1, Value: Field("request.path")}},
+			expected: "stats count(*) by `request.path`",
+		},
+		{
+			name:     "skip literals and comments",
+			query:    "# what?\nparse @message /(?<x>\\S+)/ | filter x = \"?\" and `a?` = ? | fields a / ?",
+			args:     []driver.NamedValue{{Ordinal: 1, Value: 1.5}, {Ordinal: 2, Value: int64(2)}},
+			expected: "# what?\nparse @message /(?<x>\\S+)/ | filter x = \"?\" and `a?` = 1.5 | fields a / 2",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := interpolate(c.query, c.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInterpolate__Error(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		args  []driver.NamedValue
+	}{
+		{
+			name:  "not enough parameters",
+			query: "filter a = ? and b = ?",
+			args:  []driver.NamedValue{{Ordinal: 1, Value: "x"}},
+		},
+		{
+			name:  "too many parameters",
+			query: "filter a = ?",
+			args:  []driver.NamedValue{{Ordinal: 1, Value: "x"}, {Ordinal: 2, Value: "y"}},
+		},
+		{
+			name:  "unknown named parameter",
+			query: "filter a = :a",
+			args:  []driver.NamedValue{{Name: "b", Ordinal: 1, Value: "x"}},
+		},
+		{
+			name:  "field with backtick",
+			query: "stats count(*) by ?",
+			args:  []driver.NamedValue{{Ordinal: 1, Value: Field("a` | display `b")}},
+		},
+		{
+			name:  "regex with trailing backslash",
+			query: "filter @message like ?",
+			args:  []driver.NamedValue{{Ordinal: 1, Value: Regex(`abc\`)}},
+		},
+		{
+			name:  "unterminated string",
+			query: `filter a = "? `,
+			args:  []driver.NamedValue{{Ordinal: 1, Value: "x"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := interpolate(c.query, c.args)
+			if err == nil {
+				t.Fatal("unexpected nil error")
+			}
+			t.Log(err)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// queryLimiter caps the number of in-flight Logs Insights queries of a connector.
+// The waiting queries are started in order of priority, and in order of arrival for the same priority.
+type queryLimiter struct {
+	mu       sync.Mutex
+	capacity int
+	inFlight int
+	seq      uint64
+	waiters  waiterQueue
+}
+
+// newQueryLimiter returns nil if capacity is not positive, nil limiter does not limit.
+func newQueryLimiter(capacity int) *queryLimiter {
+	if capacity <= 0 {
+		return nil
+	}
+	return &queryLimiter{capacity: capacity}
+}
+
+type waiter struct {
+	priority int64
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+// acquire waits until the query can be started. release must be called after the query is finished.
+func (l *queryLimiter) acquire(ctx context.Context, priority int64) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if l.inFlight < l.capacity && len(l.waiters) == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	l.seq++
+	w := &waiter{priority: priority, seq: l.seq, ready: make(chan struct{})}
+	heap.Push(&l.waiters, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		select {
+		case <-w.ready:
+			// the slot was handed over at the same time, give it back.
+			l.mu.Unlock()
+			l.release()
+		default:
+			heap.Remove(&l.waiters, w.index)
+			l.mu.Unlock()
+		}
+		return ctx.Err()
+	}
+}
+
+func (l *queryLimiter) release() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.waiters) > 0 {
+		// hand over the slot to the next waiter, inFlight is unchanged.
+		w := heap.Pop(&l.waiters).(*waiter)
+		close(w.ready)
+		return
+	}
+	l.inFlight--
+}
+
+// waiterQueue implements heap.Interface, the highest priority and the earliest waiter first.
+type waiterQueue []*waiter
+
+func (q waiterQueue) Len() int { return len(q) }
+
+func (q waiterQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waiterQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waiterQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waiterQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return w
+}
+
+type queryPriorityKey struct{}
+
+func withQueryPriority(ctx context.Context, priority int64) context.Context {
+	return context.WithValue(ctx, queryPriorityKey{}, priority)
+}
+
+func getQueryPriority(ctx context.Context) int64 {
+	p, _ := ctx.Value(queryPriorityKey{}).(int64)
+	return p
+}
@@ -0,0 +1,66 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueryLimiter(t *testing.T) {
+	l := newQueryLimiter(1)
+	ctx := context.Background()
+	if err := l.acquire(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []int64
+	var wg sync.WaitGroup
+	for i, priority := range []int64{0, 10, 5} {
+		wg.Add(1)
+		go func(priority int64) {
+			defer wg.Done()
+			if err := l.acquire(ctx, priority); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+			l.release()
+		}(priority)
+		waitForWaiters(t, l, i+1)
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(cctx, 100); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("unexpected error:", err)
+	}
+
+	l.release()
+	wg.Wait()
+	if !reflect.DeepEqual(order, []int64{10, 5, 0}) {
+		t.Fatal("unexpected order:", order)
+	}
+	if l.inFlight != 0 || len(l.waiters) != 0 {
+		t.Fatalf("unexpected state: in_flight=%d waiters=%d", l.inFlight, len(l.waiters))
+	}
+}
+
+func waitForWaiters(t *testing.T, l *queryLimiter, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		l.mu.Lock()
+		waiters := len(l.waiters)
+		l.mu.Unlock()
+		if waiters >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("waiters did not reach %d", n)
+}
@@ -1,10 +1,18 @@
 package cloudwatchlogsinsightsdriver
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 )
 
 type Logger interface {
@@ -39,3 +47,131 @@ func GetLogger() Logger {
 func GetDebugLogger() Logger {
 	return debugLogger
 }
+
+// The keys of the log attributes, stable across versions.
+const (
+	LogKeyQueryID        = "query_id"
+	LogKeyLogGroups      = "log_groups"
+	LogKeyStartTime      = "start_time"
+	LogKeyEndTime        = "end_time"
+	LogKeyStatus         = "status"
+	LogKeyRecordsMatched = "records_matched"
+	LogKeyRecordsScanned = "records_scanned"
+	LogKeyBytesScanned   = "bytes_scanned"
+	LogKeyDuration       = "duration"
+)
+
+// NewLegacyLogHandler returns the slog.Handler that writes to the loggers set by SetLogger and SetDebugLogger.
+// The records of slog.LevelWarn or higher are written to the error logger, and the others to the debug logger.
+// It is the default handler when CloudwatchLogsInsightsConfig.Logger is nil.
+func NewLegacyLogHandler() slog.Handler {
+	return &legacyLogHandler{}
+}
+
+type legacyLogHandler struct {
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *legacyLogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if level >= slog.LevelWarn {
+		return true
+	}
+	return debugLogger.Writer() != io.Discard
+}
+
+func (h *legacyLogHandler) Handle(_ context.Context, r slog.Record) error {
+	var queryID string
+	var b strings.Builder
+	appendAttr := func(prefix string, a slog.Attr) {
+		key := prefix + a.Key
+		if key == LogKeyQueryID {
+			queryID = a.Value.String()
+			return
+		}
+		fmt.Fprintf(&b, " %s=%v", key, a.Value)
+	}
+	// the keys of h.attrs are already prefixed by the groups of WithAttrs.
+	for _, a := range h.attrs {
+		appendAttr("", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(h.prefix, a)
+		return true
+	})
+	msg := r.Message + b.String()
+	if queryID != "" {
+		msg = "[" + queryID + "] " + msg
+	}
+	if r.Level >= slog.LevelWarn {
+		legacyOutput(errLogger, msg)
+	} else {
+		legacyOutput(debugLogger, msg)
+	}
+	return nil
+}
+
+// legacyCallDepth skips legacyOutput, Handle, and the log and Info (or the like) methods of slog.Logger,
+// so that log.Lshortfile reports the caller of slog.Logger.
+const legacyCallDepth = 5
+
+// legacyOutput writes msg by Output of *log.Logger with the caller of slog.Logger, or by Printf for the other loggers.
+func legacyOutput(l Logger, msg string) {
+	if o, ok := l.(interface{ Output(int, string) error }); ok {
+		_ = o.Output(legacyCallDepth, msg)
+		return
+	}
+	l.Printf("%s", msg)
+}
+
+func (h *legacyLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	h2.attrs = append(h2.attrs, h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+func (h *legacyLogHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// queryLogAttrs returns the attributes of the started query.
+func queryLogAttrs(queryID *string, params *cloudwatchlogs.StartQueryInput) []any {
+	attrs := []any{slog.String(LogKeyQueryID, coalesce(queryID, nullif("-")))}
+	if params == nil {
+		return attrs
+	}
+	var logGroups []string
+	switch {
+	case len(params.LogGroupIdentifiers) > 0:
+		logGroups = params.LogGroupIdentifiers
+	case len(params.LogGroupNames) > 0:
+		logGroups = params.LogGroupNames
+	case params.LogGroupName != nil:
+		logGroups = []string{*params.LogGroupName}
+	}
+	return append(attrs,
+		slog.Any(LogKeyLogGroups, logGroups),
+		slog.Time(LogKeyStartTime, time.Unix(coalesce(params.StartTime), 0)),
+		slog.Time(LogKeyEndTime, time.Unix(coalesce(params.EndTime), 0)),
+	)
+}
+
+// statisticsLogAttrs returns the attributes of the query status and statistics.
+func statisticsLogAttrs(status types.QueryStatus, stats *types.QueryStatistics, elapsed time.Duration) []any {
+	attrs := []any{slog.String(LogKeyStatus, string(status))}
+	if stats != nil {
+		attrs = append(attrs,
+			slog.Float64(LogKeyRecordsMatched, stats.RecordsMatched),
+			slog.Float64(LogKeyRecordsScanned, stats.RecordsScanned),
+			slog.Float64(LogKeyBytesScanned, stats.BytesScanned),
+		)
+	}
+	return append(attrs, slog.Duration(LogKeyDuration, elapsed))
+}
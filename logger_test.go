@@ -0,0 +1,92 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+func TestQueryContext__WithMock__SlogLogger(t *testing.T) {
+	mockClients["slog"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String("test-query-id"),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: types.QueryStatusComplete,
+				Statistics: &types.QueryStatistics{
+					RecordsMatched: 1,
+					RecordsScanned: 10,
+					BytesScanned:   100,
+				},
+			}, nil
+		},
+	}
+	connector, err := (&cloudwatchLogsInsightsDriver{}).OpenConnector("cloudwatch://?client=mock&mock=slog&log_group_name=test-log-group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	connector.(*cloudwatchLogsInsightsConnector).cfg.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	rows, err := db.QueryContext(context.Background(), "fields @message")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	if record["msg"] != "query completed" {
+		t.Fatal("unexpected record:", record)
+	}
+	for _, key := range []string{LogKeyQueryID, LogKeyLogGroups, LogKeyStartTime, LogKeyEndTime, LogKeyStatus, LogKeyRecordsMatched, LogKeyRecordsScanned, LogKeyBytesScanned, LogKeyDuration} {
+		if _, ok := record[key]; !ok {
+			t.Errorf("%s is not in the record: %v", key, record)
+		}
+	}
+	if record[LogKeyQueryID] != "test-query-id" || record[LogKeyBytesScanned] != float64(100) {
+		t.Error("unexpected record:", record)
+	}
+}
+
+func TestLegacyLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	orig := GetDebugLogger()
+	defer SetDebugLogger(orig)
+	SetDebugLogger(log.New(&buf, "", 0))
+
+	logger := slog.New(NewLegacyLogHandler()).With(slog.String(LogKeyQueryID, "test-query-id"))
+	logger.Info("query completed", slog.Float64(LogKeyRecordsScanned, 10))
+	if actual := strings.TrimSpace(buf.String()); actual != "[test-query-id] query completed records_scanned=10" {
+		t.Fatal("unexpected output:", actual)
+	}
+
+	buf.Reset()
+	logger = slog.New(NewLegacyLogHandler()).WithGroup("g").With(slog.String("k", "v")).WithGroup("h")
+	logger.Info("grouped", slog.Int("n", 1))
+	if actual := strings.TrimSpace(buf.String()); actual != "grouped g.k=v g.h.n=1" {
+		t.Fatal("unexpected output:", actual)
+	}
+
+	buf.Reset()
+	SetDebugLogger(log.New(&buf, "", log.Lshortfile))
+	slog.New(NewLegacyLogHandler()).Info("with caller")
+	if actual := strings.TrimSpace(buf.String()); !strings.HasPrefix(actual, "logger_test.go:") {
+		t.Fatal("unexpected output:", actual)
+	}
+}
@@ -0,0 +1,179 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+const defaultLogGroupCacheTTL = time.Minute
+
+// NoLogGroupCache is the LogGroupCacheTTL that disables the cache of the resolved log groups.
+// The zero value of LogGroupCacheTTL is the default, 1m.
+const NoLogGroupCache time.Duration = -1
+
+// logGroupCache caches the log group names resolved by DescribeLogGroups per connector.
+// Concurrent resolutions of the same key share a single DescribeLogGroups call.
+type logGroupCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]logGroupCacheEntry
+	calls   map[string]*logGroupCall
+}
+
+type logGroupCacheEntry struct {
+	logGroupNames []string
+	expiresAt     time.Time
+}
+
+// logGroupCall is an in-flight resolution of log group names.
+type logGroupCall struct {
+	done          chan struct{}
+	logGroupNames []string
+	err           error
+}
+
+func newLogGroupCache(ttl time.Duration) *logGroupCache {
+	return &logGroupCache{
+		ttl:     ttl,
+		entries: make(map[string]logGroupCacheEntry),
+		calls:   make(map[string]*logGroupCall),
+	}
+}
+
+// resolve returns the log group names that match the prefix or the pattern.
+// If sourceAccountIDs is not empty, the log groups in the linked source accounts are also resolved as ARNs.
+func (c *logGroupCache) resolve(ctx context.Context, client CloudwatchLogsClient, prefix, pattern string, sourceAccountIDs []string) ([]string, error) {
+	key := "prefix:" + prefix
+	if pattern != "" {
+		key = "pattern:" + pattern
+	}
+	if len(sourceAccountIDs) > 0 {
+		key += ";accounts:" + strings.Join(sourceAccountIDs, ",")
+	}
+	c.mu.Lock()
+	for {
+		if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expiresAt) {
+			c.mu.Unlock()
+			return entry.logGroupNames, nil
+		}
+		call, ok := c.calls[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		// the call is cancelled by the context of the caller that started it, not by ours, so try again.
+		if !isContextError(call.err) || ctx.Err() != nil {
+			return call.logGroupNames, call.err
+		}
+		c.mu.Lock()
+	}
+	call := &logGroupCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	call.logGroupNames, call.err = describeLogGroupNames(ctx, client, prefix, pattern, sourceAccountIDs)
+	c.mu.Lock()
+	delete(c.calls, key)
+	if call.err == nil && c.ttl > 0 {
+		c.entries[key] = logGroupCacheEntry{
+			logGroupNames: call.logGroupNames,
+			expiresAt:     time.Now().Add(c.ttl),
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.logGroupNames, call.err
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func describeLogGroupNames(ctx context.Context, client CloudwatchLogsClient, prefix, pattern string, sourceAccountIDs []string) ([]string, error) {
+	params := &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix:  nullif(prefix),
+		LogGroupNamePattern: nullif(pattern),
+	}
+	if len(sourceAccountIDs) > 0 {
+		params.IncludeLinkedAccounts = aws.Bool(true)
+		if !(len(sourceAccountIDs) == 1 && sourceAccountIDs[0] == allSourceAccounts) {
+			params.AccountIdentifiers = sourceAccountIDs
+		}
+	}
+	p := cloudwatchlogs.NewDescribeLogGroupsPaginator(client, params)
+	var logGroupNames []string
+	for p.HasMorePages() {
+		output, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describe log groups:%w", err)
+		}
+		for _, logGroup := range output.LogGroups {
+			if params.IncludeLinkedAccounts != nil && logGroup.Arn != nil {
+				logGroupNames = append(logGroupNames, strings.TrimSuffix(*logGroup.Arn, ":*"))
+				continue
+			}
+			logGroupNames = append(logGroupNames, aws.ToString(logGroup.LogGroupName))
+		}
+	}
+	return logGroupNames, nil
+}
+
+// allSourceAccounts is the source_account_ids value for all linked source accounts.
+const allSourceAccounts = "*"
+
+var accountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// normalizeLogGroups converts `account_id:name` into the log group ARN and removes the trailing `:*` of ARNs.
+// Log group names and identifiers (ARNs) can not be mixed, because StartQuery accepts only one of them.
+func normalizeLogGroups(logGroups []string, region string) ([]string, error) {
+	normalized := make([]string, 0, len(logGroups))
+	var names, identifiers int
+	for _, logGroup := range logGroups {
+		switch {
+		case isLogGroupARN(logGroup):
+			logGroup = strings.TrimSuffix(logGroup, ":*")
+			identifiers++
+		case strings.Contains(logGroup, ":") && accountIDPattern.MatchString(logGroup[:strings.IndexByte(logGroup, ':')]):
+			if region == "" {
+				return nil, fmt.Errorf("region is required for log group %q", logGroup)
+			}
+			i := strings.IndexByte(logGroup, ':')
+			logGroup = fmt.Sprintf("arn:%s:logs:%s:%s:log-group:%s", partitionForRegion(region), region, logGroup[:i], logGroup[i+1:])
+			identifiers++
+		default:
+			names++
+		}
+		normalized = append(normalized, logGroup)
+	}
+	if names > 0 && identifiers > 0 {
+		return nil, fmt.Errorf("can not mix log group names and log group identifiers (ARN or account_id:name)")
+	}
+	return normalized, nil
+}
+
+func isLogGroupARN(s string) bool {
+	return strings.HasPrefix(s, "arn:") && strings.Contains(s, ":log-group:")
+}
+
+func partitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	}
+	return "aws"
+}
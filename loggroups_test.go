@@ -0,0 +1,100 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+func TestLogGroupCache__LeaderCanceled(t *testing.T) {
+	started := make(chan struct{})
+	var calls int32
+	client := &mockCloudWatchLogsClient{
+		DescribeLogGroupsFunc: func(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return &cloudwatchlogs.DescribeLogGroupsOutput{
+				LogGroups: []types.LogGroup{{LogGroupName: aws.String("/aws/lambda/a")}},
+			}, nil
+		},
+	}
+	c := newLogGroupCache(time.Minute)
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := c.resolve(leaderCtx, client, "/aws/lambda/", "", nil)
+		leaderErr <- err
+	}()
+	<-started
+	waiterResult := make(chan []string, 1)
+	waiterErr := make(chan error, 1)
+	go func() {
+		logGroupNames, err := c.resolve(context.Background(), client, "/aws/lambda/", "", nil)
+		waiterResult <- logGroupNames
+		waiterErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatal("unexpected leader error:", err)
+	}
+	logGroupNames := <-waiterResult
+	if err := <-waiterErr; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(logGroupNames, []string{"/aws/lambda/a"}) {
+		t.Fatal("unexpected log group names:", logGroupNames)
+	}
+	if client.DescribeLogGroupsCallCount != 2 {
+		t.Fatal("unexpected DescribeLogGroups call count:", client.DescribeLogGroupsCallCount)
+	}
+}
+
+func TestLogGroupCache__Concurrent(t *testing.T) {
+	release := make(chan struct{})
+	client := &mockCloudWatchLogsClient{
+		DescribeLogGroupsFunc: func(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+			<-release
+			return &cloudwatchlogs.DescribeLogGroupsOutput{
+				LogGroups: []types.LogGroup{{LogGroupName: aws.String("/aws/lambda/a")}},
+			}, nil
+		},
+	}
+	c := newLogGroupCache(time.Minute)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logGroupNames, err := c.resolve(ctx, client, "/aws/lambda/", "", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !reflect.DeepEqual(logGroupNames, []string{"/aws/lambda/a"}) {
+				t.Error("unexpected log group names:", logGroupNames)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if _, err := c.resolve(ctx, client, "/aws/lambda/", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if client.DescribeLogGroupsCallCount != 1 {
+		t.Fatal("unexpected DescribeLogGroups call count:", client.DescribeLogGroupsCallCount)
+	}
+}
@@ -3,21 +3,27 @@ package cloudwatchlogsinsightsdriver
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 )
 
 type mockCloudWatchLogsClient struct {
-	StartQueryCallCount      int
-	StartQueryFunc           func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
-	GetQueryResultsCallCount int
-	GetQueryResultsFunc      func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
-	StopQueryCallCount       int
-	StopQueryFunc            func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error)
+	mu                         sync.Mutex
+	StartQueryCallCount        int
+	StartQueryFunc             func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
+	GetQueryResultsCallCount   int
+	GetQueryResultsFunc        func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
+	StopQueryCallCount         int
+	StopQueryFunc              func(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error)
+	DescribeLogGroupsCallCount int
+	DescribeLogGroupsFunc      func(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error)
 }
 
 func (m *mockCloudWatchLogsClient) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	m.mu.Lock()
 	m.StartQueryCallCount++
+	m.mu.Unlock()
 	if m.StartQueryFunc == nil {
 		return nil, fmt.Errorf("unexpected call to StartQueryFunc")
 	}
@@ -25,7 +31,9 @@ func (m *mockCloudWatchLogsClient) StartQuery(ctx context.Context, params *cloud
 }
 
 func (m *mockCloudWatchLogsClient) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	m.mu.Lock()
 	m.GetQueryResultsCallCount++
+	m.mu.Unlock()
 	if m.GetQueryResultsFunc == nil {
 		return nil, fmt.Errorf("unexpected call to GetQueryResultsFunc")
 	}
@@ -33,9 +41,21 @@ func (m *mockCloudWatchLogsClient) GetQueryResults(ctx context.Context, params *
 }
 
 func (m *mockCloudWatchLogsClient) StopQuery(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+	m.mu.Lock()
 	m.StopQueryCallCount++
+	m.mu.Unlock()
 	if m.StopQueryFunc == nil {
 		return nil, fmt.Errorf("unexpected call to StopQueryFunc")
 	}
 	return m.StopQueryFunc(ctx, params, optFns...)
 }
+
+func (m *mockCloudWatchLogsClient) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	m.mu.Lock()
+	m.DescribeLogGroupsCallCount++
+	m.mu.Unlock()
+	if m.DescribeLogGroupsFunc == nil {
+		return nil, fmt.Errorf("unexpected call to DescribeLogGroupsFunc")
+	}
+	return m.DescribeLogGroupsFunc(ctx, params, optFns...)
+}
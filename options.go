@@ -0,0 +1,313 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// queryOptions is the per query options, built from the config and the named parameters.
+type queryOptions struct {
+	startTime     time.Time
+	endTime       time.Time
+	logGroupNames []string
+	limit         *int32
+
+	logGroupPrefix   string
+	logGroupPattern  string
+	sourceAccountIDs []string
+
+	splitInterval time.Duration
+	concurrency   int
+
+	partitionInterval  time.Duration
+	partitionLogGroups int
+
+	shardErrorPolicy ShardErrorPolicy
+
+	maxBytesScanned   int64
+	maxRecordsScanned int64
+	scanBudgetPolicy  ScanBudgetPolicy
+
+	queryID     string
+	noWait      bool
+	stopOnAbort bool
+
+	priority int64
+	timeout  time.Duration
+	stream   bool
+}
+
+// newQueryOptions separates reserved named parameters from bind parameters.
+// The reserved named parameters are applied to queryOptions, the rest are returned as bind parameters.
+func newQueryOptions(cfg *CloudwatchLogsInsightsConfig, args []driver.NamedValue) (*queryOptions, []driver.NamedValue, error) {
+	now := time.Now()
+	opts := &queryOptions{
+		endTime:       now,
+		limit:         cfg.Limit,
+		splitInterval: cfg.SplitInterval,
+		concurrency:   cfg.Concurrency,
+
+		partitionInterval:  cfg.PartitionInterval,
+		partitionLogGroups: cfg.PartitionLogGroups,
+
+		shardErrorPolicy: cfg.ShardErrorPolicy,
+
+		maxBytesScanned:   cfg.MaxBytesScanned,
+		maxRecordsScanned: cfg.MaxRecordsScanned,
+		scanBudgetPolicy:  cfg.ScanBudgetPolicy,
+	}
+	var binds []driver.NamedValue
+	var err error
+	var names []string
+	var stopOnAbortSet bool
+	for _, arg := range args {
+		if arg.Name != "" {
+			names = append(names, arg.Name)
+		}
+		switch arg.Name {
+		case "start_time", "end_time":
+			t, err := parseTimeExpr(arg.Value, now, cfg.Location)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s cannot be parsed: %w", arg.Name, err)
+			}
+			if arg.Name == "start_time" {
+				opts.startTime = t
+			} else {
+				opts.endTime = t
+			}
+		case "log_group_name":
+			v, ok := arg.Value.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("log_group_name must be string")
+			}
+			opts.logGroupNames = append(opts.logGroupNames, v)
+		case "log_group_names":
+			switch v := arg.Value.(type) {
+			case []string:
+				opts.logGroupNames = append(opts.logGroupNames, v...)
+			case string:
+				opts.logGroupNames = append(opts.logGroupNames, strings.Split(v, ",")...)
+			default:
+				return nil, nil, fmt.Errorf("log_group_names must be []string or string")
+			}
+		case "log_group_prefix":
+			v, ok := arg.Value.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("log_group_prefix must be string")
+			}
+			opts.logGroupPrefix = v
+		case "log_group_pattern":
+			v, ok := arg.Value.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("log_group_pattern must be string")
+			}
+			opts.logGroupPattern = v
+		case "source_account_ids":
+			switch v := arg.Value.(type) {
+			case []string:
+				opts.sourceAccountIDs = v
+			case string:
+				opts.sourceAccountIDs = strings.Split(v, ",")
+			default:
+				return nil, nil, fmt.Errorf("source_account_ids must be []string or string")
+			}
+		case "limit":
+			var v int64
+			switch n := arg.Value.(type) {
+			case int64:
+				v = n
+			case int:
+				v = int64(n)
+			default:
+				return nil, nil, fmt.Errorf("limit must be integer")
+			}
+			if v <= 0 || v > maxQueryResults {
+				return nil, nil, fmt.Errorf("limit must be between 1 and %d", maxQueryResults)
+			}
+			opts.limit = aws.Int32(int32(v))
+		case "split_interval":
+			opts.splitInterval, err = parseDurationArg(arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			if opts.splitInterval < time.Second {
+				return nil, nil, fmt.Errorf("split_interval must be at least 1s")
+			}
+		case "concurrency":
+			v, ok := arg.Value.(int64)
+			if !ok || v <= 0 {
+				return nil, nil, fmt.Errorf("concurrency must be positive integer")
+			}
+			opts.concurrency = int(v)
+		case "partition_interval":
+			opts.partitionInterval, err = parseDurationArg(arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			if opts.partitionInterval < time.Second {
+				return nil, nil, fmt.Errorf("partition_interval must be at least 1s")
+			}
+		case "partition_log_groups":
+			v, ok := arg.Value.(int64)
+			if !ok || v <= 0 {
+				return nil, nil, fmt.Errorf("partition_log_groups must be positive integer")
+			}
+			opts.partitionLogGroups = int(v)
+		case "shard_error_policy":
+			v, ok := arg.Value.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("shard_error_policy must be string")
+			}
+			if opts.shardErrorPolicy, err = parseShardErrorPolicy(v); err != nil {
+				return nil, nil, err
+			}
+		case "max_bytes_scanned", "max_records_scanned":
+			v, ok := arg.Value.(int64)
+			if !ok || v < 0 {
+				return nil, nil, fmt.Errorf("%s must be non-negative integer", arg.Name)
+			}
+			if arg.Name == "max_bytes_scanned" {
+				opts.maxBytesScanned = v
+			} else {
+				opts.maxRecordsScanned = v
+			}
+		case "query_id":
+			v, ok := arg.Value.(string)
+			if !ok || v == "" {
+				return nil, nil, fmt.Errorf("query_id must be non-empty string")
+			}
+			opts.queryID = v
+		case "stop_on_abort":
+			v, ok := arg.Value.(bool)
+			if !ok {
+				return nil, nil, fmt.Errorf("stop_on_abort must be bool")
+			}
+			opts.stopOnAbort = v
+			stopOnAbortSet = true
+		case "timeout":
+			opts.timeout, err = parseDurationArg(arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			if opts.timeout <= 0 {
+				return nil, nil, fmt.Errorf("timeout must be positive")
+			}
+		case "stream":
+			v, ok := arg.Value.(bool)
+			if !ok {
+				return nil, nil, fmt.Errorf("stream must be bool")
+			}
+			opts.stream = v
+		case "priority":
+			v, ok := arg.Value.(int64)
+			if !ok {
+				return nil, nil, fmt.Errorf("priority must be integer")
+			}
+			opts.priority = v
+		case "wait":
+			v, ok := arg.Value.(bool)
+			if !ok {
+				return nil, nil, fmt.Errorf("wait must be bool")
+			}
+			opts.noWait = !v
+		case "scan_budget_policy":
+			v, ok := arg.Value.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("scan_budget_policy must be string")
+			}
+			if opts.scanBudgetPolicy, err = parseScanBudgetPolicy(v); err != nil {
+				return nil, nil, err
+			}
+		default:
+			binds = append(binds, arg)
+		}
+	}
+	if opts.startTime.IsZero() {
+		defaultRange := cfg.DefaultRange
+		if defaultRange <= 0 {
+			defaultRange = 15 * time.Minute
+		}
+		opts.startTime = opts.endTime.Add(-defaultRange)
+	}
+	if opts.startTime.After(opts.endTime) {
+		return nil, nil, fmt.Errorf("start_time %s is after end_time %s", opts.startTime.Format(time.RFC3339), opts.endTime.Format(time.RFC3339))
+	}
+	if opts.stream && (opts.queryID != "" || opts.noWait) {
+		return nil, nil, fmt.Errorf("can not set stream with query_id or wait=false")
+	}
+	if opts.queryID != "" {
+		if opts.noWait {
+			return nil, nil, fmt.Errorf("can not set query_id and wait=false at the same time")
+		}
+		if len(binds) > 0 {
+			return nil, nil, fmt.Errorf("can not set bind parameters with query_id")
+		}
+		for _, name := range names {
+			if !attachQueryOptionNames[name] {
+				return nil, nil, fmt.Errorf("can not set %s with query_id", name)
+			}
+		}
+		// the query is already started, the log groups are not needed.
+		return opts, binds, nil
+	}
+	if stopOnAbortSet {
+		return nil, nil, fmt.Errorf("stop_on_abort can be set only with query_id")
+	}
+	if len(opts.logGroupNames) == 0 && opts.logGroupPrefix == "" && opts.logGroupPattern == "" {
+		if len(cfg.LogGroupNames) == 0 && cfg.LogGroupPrefix == "" && cfg.LogGroupPattern == "" {
+			return nil, nil, fmt.Errorf("log_group_name is required")
+		}
+		opts.logGroupNames = cfg.LogGroupNames
+		opts.logGroupPrefix = cfg.LogGroupPrefix
+		opts.logGroupPattern = cfg.LogGroupPattern
+	}
+	if opts.sourceAccountIDs == nil {
+		opts.sourceAccountIDs = cfg.SourceAccountIDs
+	}
+	if opts.logGroupPrefix != "" && opts.logGroupPattern != "" {
+		return nil, nil, fmt.Errorf("can not set log_group_prefix and log_group_pattern at the same time")
+	}
+	if len(opts.sourceAccountIDs) > 0 && opts.logGroupPrefix == "" && opts.logGroupPattern == "" {
+		return nil, nil, fmt.Errorf("source_account_ids requires log_group_prefix or log_group_pattern")
+	}
+	return opts, binds, nil
+}
+
+// attachQueryOptionNames are the named parameters that can be set with query_id.
+// The others are the options of StartQuery, which have no effect on the started query.
+var attachQueryOptionNames = map[string]bool{
+	"query_id":            true,
+	"stop_on_abort":       true,
+	"timeout":             true,
+	"priority":            true,
+	"max_bytes_scanned":   true,
+	"max_records_scanned": true,
+	"scan_budget_policy":  true,
+}
+
+// concurrencyOr returns the concurrency, or def if concurrency is not specified.
+func (opts *queryOptions) concurrencyOr(def int) int {
+	if opts.concurrency > 0 {
+		return opts.concurrency
+	}
+	return def
+}
+
+func parseDurationArg(arg driver.NamedValue) (time.Duration, error) {
+	switch v := arg.Value.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("%s cannot be parsed: %w", arg.Name, err)
+		}
+		return d, nil
+	default:
+		return 0, fmt.Errorf("%s must be time.Duration or string", arg.Name)
+	}
+}
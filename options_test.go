@@ -0,0 +1,72 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestNewQueryOptions__Error(t *testing.T) {
+	cfg := &CloudwatchLogsInsightsConfig{
+		LogGroupNames: []string{"test-log-group"},
+		Location:      time.UTC,
+	}
+	cases := []struct {
+		name string
+		args []driver.NamedValue
+	}{
+		{
+			name: "limit overflow",
+			args: []driver.NamedValue{{Name: "limit", Value: int64(1 << 32)}},
+		},
+		{
+			name: "limit not positive",
+			args: []driver.NamedValue{{Name: "limit", Value: int64(0)}},
+		},
+		{
+			name: "start_time after end_time",
+			args: []driver.NamedValue{
+				{Name: "start_time", Value: "2024-01-02T00:00:00Z"},
+				{Name: "end_time", Value: "2024-01-01T00:00:00Z"},
+			},
+		},
+		{
+			name: "start_time after end_time by expression",
+			args: []driver.NamedValue{
+				{Name: "start_time", Value: "now"},
+				{Name: "end_time", Value: "now-1h"},
+			},
+		},
+		{
+			name: "query_id with bind parameters",
+			args: []driver.NamedValue{
+				{Name: "query_id", Value: "test-query-id"},
+				{Name: "level", Value: "ERROR"},
+			},
+		},
+		{
+			name: "query_id with start_time",
+			args: []driver.NamedValue{
+				{Name: "query_id", Value: "test-query-id"},
+				{Name: "start_time", Value: "now-1h"},
+			},
+		},
+		{
+			name: "stop_on_abort without query_id",
+			args: []driver.NamedValue{{Name: "stop_on_abort", Value: true}},
+		},
+		{
+			name: "source_account_ids without log_group_prefix or log_group_pattern",
+			args: []driver.NamedValue{{Name: "source_account_ids", Value: "123456789012"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := newQueryOptions(cfg, c.args)
+			if err == nil {
+				t.Fatal("unexpected nil error")
+			}
+			t.Log(err)
+		})
+	}
+}
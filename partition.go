@@ -0,0 +1,85 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+type partition struct {
+	window        timeWindow
+	logGroupNames []string
+}
+
+// partitionWindows splits [start, end] into non-overlapping windows of interval seconds.
+// Unlike splitWindows, a record must not be counted twice in stats.
+func partitionWindows(start, end, interval int64) []timeWindow {
+	windows := splitWindows(start, end, interval)
+	for i := 0; i < len(windows)-1; i++ {
+		windows[i].end--
+	}
+	return windows
+}
+
+// partitionLogGroupNames splits the log group names into batches of size.
+func partitionLogGroupNames(logGroupNames []string, size int) [][]string {
+	if size <= 0 || len(logGroupNames) <= size {
+		return [][]string{logGroupNames}
+	}
+	batches := make([][]string, 0, (len(logGroupNames)+size-1)/size)
+	for i := 0; i < len(logGroupNames); i += size {
+		end := i + size
+		if end > len(logGroupNames) {
+			end = len(logGroupNames)
+		}
+		batches = append(batches, logGroupNames[i:end])
+	}
+	return batches
+}
+
+// partitionedStatsQuery runs the stats query for each partition of time windows and log group batches,
+// and re-aggregates the partial results.
+// ErrResultsTruncated is returned if a partition hits maxQueryResults groups.
+func (conn *cloudwatchLogsInsightsConn) partitionedStatsQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, opts *queryOptions, sq *statsQuery) ([][]types.ResultField, error) {
+	windows := partitionWindows(aws.ToInt64(params.StartTime), aws.ToInt64(params.EndTime), int64(opts.partitionInterval.Seconds()))
+	batches := partitionLogGroupNames(opts.logGroupNames, opts.partitionLogGroups)
+	partitions := make([]partition, 0, len(windows)*len(batches))
+	for _, w := range windows {
+		for _, batch := range batches {
+			partitions = append(partitions, partition{window: w, logGroupNames: batch})
+		}
+	}
+	partialQuery := sq.partialQuery()
+	conn.logger.DebugContext(ctx, "partitioned stats query", slog.Int("partitions", len(partitions)), slog.Int("concurrency", opts.concurrencyOr(1)), slog.String("query", partialQuery))
+
+	partials := make([][][]types.ResultField, len(partitions))
+	err := runConcurrently(ctx, len(partitions), opts.concurrencyOr(1), func(ctx context.Context, i int) error {
+		p := partitions[i]
+		partitionParams := *params
+		partitionParams.QueryString = aws.String(partialQuery)
+		partitionParams.StartTime = aws.Int64(p.window.start)
+		partitionParams.EndTime = aws.Int64(p.window.end)
+		partitionParams.Limit = aws.Int32(maxQueryResults)
+		setLogGroups(&partitionParams, p.logGroupNames)
+		output, err := conn.startQuery(ctx, &partitionParams)
+		if err != nil {
+			return err
+		}
+		if len(output.Results) >= maxQueryResults {
+			// the missing groups would make the merged aggregates wrong.
+			return fmt.Errorf("partition %s - %s has %d or more groups, use smaller partition_interval or partition_log_groups: %w",
+				time.Unix(p.window.start, 0).UTC().Format(time.RFC3339), time.Unix(p.window.end, 0).UTC().Format(time.RFC3339), maxQueryResults, ErrResultsTruncated)
+		}
+		partials[i] = output.Results
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sq.merge(partials), nil
+}
@@ -0,0 +1,172 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// PollState is the state of the query passed to PollStrategy.
+type PollState struct {
+	Attempt            int // the number of GetQueryResults calls so far, 1-origin.
+	Elapsed            time.Duration
+	PreviousInterval   time.Duration // 0 for the first interval.
+	Statistics         *types.QueryStatistics
+	PreviousStatistics *types.QueryStatistics
+}
+
+// PollStrategy decides the interval of GetQueryResults calls while the query is running.
+type PollStrategy interface {
+	NextInterval(state PollState) time.Duration
+}
+
+// FixedPollStrategy polls at the fixed interval. This is the default, with the polling interval.
+type FixedPollStrategy struct {
+	Interval time.Duration
+}
+
+func (s FixedPollStrategy) NextInterval(_ PollState) time.Duration {
+	return s.Interval
+}
+
+// ExponentialPollStrategy polls at the interval multiplied by Multiplier (default 2) for each poll, up to Max.
+type ExponentialPollStrategy struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+func (s ExponentialPollStrategy) NextInterval(state PollState) time.Duration {
+	if state.PreviousInterval <= 0 {
+		return s.Initial
+	}
+	multiplier := s.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	return capInterval(time.Duration(float64(state.PreviousInterval)*multiplier), s.Initial, s.Max)
+}
+
+// StatisticsPollStrategy polls at a quarter of the elapsed time while the statistics show that the query is scanning records,
+// so short queries are polled quickly and long queries are polled rarely.
+// While the statistics do not change, for example the query is scheduled, the interval is doubled.
+// The interval is between Min and Max.
+type StatisticsPollStrategy struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (s StatisticsPollStrategy) NextInterval(state PollState) time.Duration {
+	if state.PreviousInterval <= 0 {
+		return s.Min
+	}
+	if state.Statistics == nil || state.PreviousStatistics == nil ||
+		state.Statistics.RecordsScanned == state.PreviousStatistics.RecordsScanned {
+		return capInterval(2*state.PreviousInterval, s.Min, s.Max)
+	}
+	return capInterval(state.Elapsed/4, s.Min, s.Max)
+}
+
+func capInterval(d, min, max time.Duration) time.Duration {
+	if max > 0 && d > max {
+		d = max
+	}
+	if d < min {
+		d = min
+	}
+	return d
+}
+
+const (
+	pollStrategyFixed       = "fixed"
+	pollStrategyExponential = "exponential"
+	pollStrategyStatistics  = "statistics"
+)
+
+func newPollStrategy(name string, polling, maxInterval time.Duration) (PollStrategy, error) {
+	switch name {
+	case pollStrategyFixed:
+		return FixedPollStrategy{Interval: polling}, nil
+	case pollStrategyExponential:
+		return ExponentialPollStrategy{Initial: polling, Max: maxInterval}, nil
+	case pollStrategyStatistics:
+		return StatisticsPollStrategy{Min: polling, Max: maxInterval}, nil
+	}
+	return nil, fmt.Errorf("unknown poll_strategy %q", name)
+}
+
+// pollStrategyName returns the DSN name of the built-in strategy, or empty for a custom strategy.
+func pollStrategyName(s PollStrategy) string {
+	switch s.(type) {
+	case FixedPollStrategy:
+		return pollStrategyFixed
+	case ExponentialPollStrategy:
+		return pollStrategyExponential
+	case StatisticsPollStrategy:
+		return pollStrategyStatistics
+	}
+	return ""
+}
+
+// tokenBucket is the rate limiter of GetQueryResults shared by the connections of a connector.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns nil if rate is not positive, nil token bucket does not limit.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token, waiting until it is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	// reserve the token, the tokens may be negative while the reserved callers are waiting.
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,94 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+func TestPollStrategy(t *testing.T) {
+	scanned := func(records float64) *types.QueryStatistics {
+		return &types.QueryStatistics{RecordsScanned: records}
+	}
+	cases := []struct {
+		name     string
+		strategy PollStrategy
+		state    PollState
+		expected time.Duration
+	}{
+		{"fixed", FixedPollStrategy{Interval: time.Second}, PollState{PreviousInterval: 3 * time.Second}, time.Second},
+		{"exponential first", ExponentialPollStrategy{Initial: 100 * time.Millisecond, Max: time.Second}, PollState{}, 100 * time.Millisecond},
+		{"exponential", ExponentialPollStrategy{Initial: 100 * time.Millisecond, Max: time.Second}, PollState{PreviousInterval: 400 * time.Millisecond}, 800 * time.Millisecond},
+		{"exponential max", ExponentialPollStrategy{Initial: 100 * time.Millisecond, Max: time.Second}, PollState{PreviousInterval: 800 * time.Millisecond}, time.Second},
+		{"statistics first", StatisticsPollStrategy{Min: 100 * time.Millisecond, Max: 5 * time.Second}, PollState{}, 100 * time.Millisecond},
+		{"statistics scanning", StatisticsPollStrategy{Min: 100 * time.Millisecond, Max: 5 * time.Second}, PollState{
+			Elapsed: 8 * time.Second, PreviousInterval: time.Second, Statistics: scanned(200), PreviousStatistics: scanned(100),
+		}, 2 * time.Second},
+		{"statistics no progress", StatisticsPollStrategy{Min: 100 * time.Millisecond, Max: 5 * time.Second}, PollState{
+			Elapsed: 8 * time.Second, PreviousInterval: 300 * time.Millisecond, Statistics: scanned(100), PreviousStatistics: scanned(100),
+		}, 600 * time.Millisecond},
+		{"statistics max", StatisticsPollStrategy{Min: 100 * time.Millisecond, Max: 5 * time.Second}, PollState{
+			Elapsed: time.Minute, PreviousInterval: time.Second, Statistics: scanned(200), PreviousStatistics: scanned(100),
+		}, 5 * time.Second},
+	}
+	for _, c := range cases {
+		if actual := c.strategy.NextInterval(c.state); actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestConfigParseDSN__PollStrategy(t *testing.T) {
+	cfg, err := ParseDSN("cloudwatch://?polling=200ms&poll_strategy=exponential&poll_max_interval=3s&get_query_results_rate=2.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ExponentialPollStrategy{Initial: 200 * time.Millisecond, Max: 3 * time.Second}
+	if cfg.PollStrategy != expected {
+		t.Errorf("unexpected poll strategy: %#v", cfg.PollStrategy)
+	}
+	cfg2, err := ParseDSN(cfg.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg2.PollStrategy != expected || cfg2.GetQueryResultsRate != 2.5 {
+		t.Errorf("unexpected round trip: %s", cfg.String())
+	}
+	cfg, err = ParseDSN("cloudwatch://?poll_strategy=statistics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (StatisticsPollStrategy{Min: 100 * time.Millisecond, Max: 5 * time.Second}); cfg.PollStrategy != expected {
+		t.Errorf("unexpected default poll strategy: %#v", cfg.PollStrategy)
+	}
+	if _, err := ParseDSN("cloudwatch://?poll_strategy=random"); err == nil {
+		t.Error("unexpected nil error")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(100, 2)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := b.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 tokens of the burst, and 2 tokens at 100/s.
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("unexpected elapsed time: %s", elapsed)
+	}
+
+	b = newTokenBucket(0.001, 1)
+	if err := b.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := b.wait(cctx); err != context.DeadlineExceeded {
+		t.Fatal("unexpected error:", err)
+	}
+}
@@ -0,0 +1,40 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// QueryProgress is the progress of a running Logs Insights query, reported on every poll of GetQueryResults.
+type QueryProgress struct {
+	QueryStatistics
+	Status types.QueryStatus
+}
+
+type progressHookKey struct{}
+
+// WithQueryProgressHook returns the context that calls hook on every poll of the Logs Insights queries
+// executed by db.QueryContext with the context.
+// The hook is called from the goroutines of the queries, and should return quickly.
+func WithQueryProgressHook(ctx context.Context, hook func(QueryProgress)) context.Context {
+	if parent, ok := ctx.Value(progressHookKey{}).(func(QueryProgress)); ok {
+		child := hook
+		hook = func(p QueryProgress) {
+			parent(p)
+			child(p)
+		}
+	}
+	return context.WithValue(ctx, progressHookKey{}, hook)
+}
+
+func reportQueryProgress(ctx context.Context, queryID string, output *cloudwatchlogs.GetQueryResultsOutput, elapsed time.Duration) {
+	if hook, ok := ctx.Value(progressHookKey{}).(func(QueryProgress)); ok {
+		hook(QueryProgress{
+			QueryStatistics: newQueryStatistics(queryID, output.Statistics, elapsed),
+			Status:          output.Status,
+		})
+	}
+}
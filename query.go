@@ -0,0 +1,265 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// queryCommand is a command of the query pipeline, for example `stats count(*) by bin(5m)`.
+type queryCommand struct {
+	name string // lower-cased command name
+	text string
+}
+
+// splitCommands splits the query by `|` outside of literals, comments and parentheses.
+func splitCommands(query string) ([]queryCommand, error) {
+	parts, err := splitTopLevel(query, '|')
+	if err != nil {
+		return nil, err
+	}
+	commands := make([]queryCommand, 0, len(parts))
+	for _, part := range parts {
+		text := strings.TrimSpace(stripComments(part))
+		if text == "" {
+			continue
+		}
+		name := text
+		if i := strings.IndexFunc(text, func(r rune) bool { return r < 0x80 && !isIdentChar(byte(r)) }); i >= 0 {
+			name = text[:i]
+		}
+		commands = append(commands, queryCommand{
+			name: strings.ToLower(name),
+			text: text,
+		})
+	}
+	return commands, nil
+}
+
+// stripComments removes `#` comments, which would comment out the following commands after joining.
+func stripComments(s string) string {
+	if !strings.Contains(s, "#") {
+		return s
+	}
+	var b strings.Builder
+	pos := 0
+	// the unterminated literal is reported by splitTopLevel, so the error is ignored here.
+	_ = walkQuery(s, func(i int) int {
+		if s[i] != '#' {
+			return i
+		}
+		b.WriteString(s[pos:i])
+		end := strings.IndexByte(s[i:], '\n')
+		if end < 0 {
+			pos = len(s)
+			return len(s)
+		}
+		pos = i + end
+		return pos - 1
+	})
+	b.WriteString(s[pos:])
+	return b.String()
+}
+
+// splitTopLevel splits s by sep outside of literals, comments and parentheses.
+func splitTopLevel(s string, sep byte) ([]string, error) {
+	var parts []string
+	var depth, pos int
+	err := walkQuery(s, func(i int) int {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[pos:i])
+				pos = i + 1
+			}
+		}
+		return i
+	})
+	if err != nil {
+		return nil, err
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in %q", s)
+	}
+	return append(parts, s[pos:]), nil
+}
+
+// indexTopLevelWord returns the offset of the first case-insensitive word outside of literals and parentheses, or -1.
+func indexTopLevelWord(s, word string) int {
+	index := -1
+	var depth int
+	// the unterminated literal is reported by splitTopLevel, so the error is ignored here.
+	_ = walkQuery(s, func(i int) int {
+		if index >= 0 {
+			return len(s)
+		}
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		default:
+			if depth != 0 || i+len(word) > len(s) || !strings.EqualFold(s[i:i+len(word)], word) {
+				return i
+			}
+			if i > 0 && (isIdentChar(s[i-1]) || s[i-1] == '@' || s[i-1] == '.') {
+				return i
+			}
+			if end := i + len(word); end < len(s) && isIdentChar(s[end]) {
+				return i
+			}
+			index = i
+		}
+		return i
+	})
+	return index
+}
+
+// splitAlias splits `expr as alias` into expr and alias. alias is empty if there is no `as`.
+func splitAlias(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := indexTopLevelWord(s, "as")
+	if i < 0 {
+		return s, ""
+	}
+	return strings.TrimSpace(s[:i]), unquoteField(strings.TrimSpace(s[i+2:]))
+}
+
+func unquoteField(s string) string {
+	if len(s) >= 2 && s[0] == '`' && s[len(s)-1] == '`' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// resultOrder is the sort and limit commands of the query,
+// which are applied again to the results merged from several queries.
+type resultOrder struct {
+	sortKeys []sortKey
+	limit    int
+}
+
+// parseResultOrder returns the sort and limit commands of the query.
+// Logs Insights returns the newest records first without the sort command.
+func parseResultOrder(query string) (resultOrder, error) {
+	commands, err := splitCommands(query)
+	if err != nil {
+		return resultOrder{}, err
+	}
+	order := resultOrder{sortKeys: []sortKey{{column: "@timestamp", desc: true}}}
+	for _, cmd := range commands {
+		switch cmd.name {
+		case "sort":
+			if order.sortKeys, err = parseSort(cmd.text); err != nil {
+				return resultOrder{}, err
+			}
+		case "limit":
+			if order.limit, err = parseLimit(cmd.text); err != nil {
+				return resultOrder{}, err
+			}
+		}
+	}
+	return order, nil
+}
+
+// apply sorts the merged results, and truncates them by the limit command and the limit option.
+func (order resultOrder) apply(results [][]types.ResultField, limit *int32) [][]types.ResultField {
+	sortResults(results, order.sortKeys)
+	if order.limit > 0 && len(results) > order.limit {
+		results = results[:order.limit]
+	}
+	if limit != nil && len(results) > int(*limit) {
+		results = results[:*limit]
+	}
+	return results
+}
+
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// parseSort parses the `sort` command. The default order is ascending.
+func parseSort(text string) ([]sortKey, error) {
+	body := strings.TrimSpace(text[len("sort"):])
+	parts, err := splitTopLevel(body, ',')
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]sortKey, 0, len(parts))
+	for _, part := range parts {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid sort command: %q", text)
+		}
+		key := sortKey{column: unquoteField(strings.Join(fields, " "))}
+		switch strings.ToLower(fields[len(fields)-1]) {
+		case "desc":
+			key.desc = true
+			key.column = unquoteField(strings.Join(fields[:len(fields)-1], " "))
+		case "asc":
+			key.column = unquoteField(strings.Join(fields[:len(fields)-1], " "))
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseLimit parses the `limit` command.
+func parseLimit(text string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(text[len("limit"):]))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid limit command: %q", text)
+	}
+	return n, nil
+}
+
+// sortResults sorts the results by the sort keys.
+// Values are compared as numbers if both are numeric, and missing values are placed last.
+func sortResults(results [][]types.ResultField, keys []sortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, key := range keys {
+			a, b := resultFieldValue(results[i], key.column), resultFieldValue(results[j], key.column)
+			if a == nil || b == nil {
+				if a == nil && b == nil {
+					continue
+				}
+				return b == nil
+			}
+			c := compareResultValues(*a, *b)
+			if c == 0 {
+				continue
+			}
+			if key.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func compareResultValues(a, b string) int {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,107 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/smithy-go"
+)
+
+// RetryPolicy is the retry policy with exponential backoff and full jitter for the retryable errors,
+// such as LimitExceededException and ThrottlingException.
+type RetryPolicy struct {
+	MaxAttempts int           // including the first attempt, 1 or less means no retry.
+	BaseDelay   time.Duration // the upper bound of the first backoff, doubled for each retry.
+	MaxDelay    time.Duration // the upper bound of the backoff, 0 means no upper bound.
+}
+
+// DefaultStartRetryPolicy is the default retry policy for StartQuery.
+// LimitExceededException of StartQuery lasts until the running queries are finished, so the backoff is long.
+var DefaultStartRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// DefaultPollRetryPolicy is the default retry policy for GetQueryResults.
+var DefaultPollRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+var retryableErrorCodes = map[string]bool{
+	"LimitExceededException":      true,
+	"ThrottlingException":         true,
+	"Throttling":                  true,
+	"ThrottledException":          true,
+	"TooManyRequestsException":    true,
+	"RequestLimitExceeded":        true,
+	"ServiceUnavailableException": true,
+	"ServiceUnavailable":          true,
+}
+
+func isRetryableError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return retryableErrorCodes[apiErr.ErrorCode()]
+	}
+	return false
+}
+
+// sdkRetryer is the retryer of the AWS SDK that does not retry the errors retried by RetryPolicy,
+// so that the retries of the SDK do not stack on the retries of the policy.
+type sdkRetryer struct {
+	aws.Retryer
+}
+
+func (r sdkRetryer) IsErrorRetryable(err error) bool {
+	return !isRetryableError(err) && r.Retryer.IsErrorRetryable(err)
+}
+
+// withoutSDKRetry is the option of an API call retried by RetryPolicy.
+func withoutSDKRetry(o *cloudwatchlogs.Options) {
+	if o.Retryer != nil {
+		o.Retryer = sdkRetryer{Retryer: o.Retryer}
+	}
+}
+
+// backoff returns the delay before the retry of the attempt (1-origin).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// do calls fn until it succeeds, returns a non-retryable error, or MaxAttempts is reached.
+// The backoff delays are not limited by the timeout of each attempt, but by ctx.
+func (p RetryPolicy) do(ctx context.Context, logger *slog.Logger, name string, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !isRetryableError(err) {
+			return err
+		}
+		delay := p.backoff(attempt)
+		logger.DebugContext(ctx, name+": retry", slog.Duration("delay", delay), slog.Int("attempt", attempt), slog.Any("error", err))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -3,16 +3,22 @@ package cloudwatchlogsinsightsdriver
 import (
 	"database/sql/driver"
 	"io"
+	"reflect"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 )
 
 type cloudWatchLogsInsightsRows struct {
-	columns []string
-	rows    [][]driver.Value
-	index   int
+	columns     []string
+	columnTypes []columnType
+	rows        [][]driver.Value
+	index       int
+	statistics  []QueryStatistics
+	err         error // returned instead of io.EOF after the last row
 }
 
 func (r *cloudWatchLogsInsightsRows) Columns() []string {
@@ -25,6 +31,9 @@ func (r *cloudWatchLogsInsightsRows) Close() error {
 
 func (r *cloudWatchLogsInsightsRows) Next(dest []driver.Value) error {
 	if r.index >= len(r.rows) {
+		if r.err != nil {
+			return r.err
+		}
 		return io.EOF
 	}
 
@@ -38,49 +47,238 @@ func (r *cloudWatchLogsInsightsRows) Next(dest []driver.Value) error {
 	return nil
 }
 
-func newRows(output *cloudwatchlogs.GetQueryResultsOutput) *cloudWatchLogsInsightsRows {
-	results := output.Results
+// QueryStatistics implements QueryStatisticsProvider.
+func (r *cloudWatchLogsInsightsRows) QueryStatistics() []QueryStatistics {
+	return r.statistics
+}
+
+// ColumnTypeScanType implements driver.RowsColumnTypeScanType.
+func (r *cloudWatchLogsInsightsRows) ColumnTypeScanType(index int) reflect.Type {
+	return r.columnTypes[index].scanType
+}
+
+// ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName.
+func (r *cloudWatchLogsInsightsRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.columnTypes[index].databaseTypeName
+}
+
+// ColumnTypeNullable implements driver.RowsColumnTypeNullable.
+// Logs Insights omits empty fields, so every column is nullable.
+func (r *cloudWatchLogsInsightsRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return true, true
+}
+
+func newRows(results [][]types.ResultField) *cloudWatchLogsInsightsRows {
 	if len(results) == 0 {
 		return &cloudWatchLogsInsightsRows{
-			columns: make([]string, 0),
-			rows:    make([][]driver.Value, 0),
-			index:   0,
+			columns:     make([]string, 0),
+			columnTypes: make([]columnType, 0),
+			rows:        make([][]driver.Value, 0),
+			index:       0,
 		}
 	}
-	columns := make([]string, 0, len(results[0]))
-	index := make(map[int]int, len(results[0]))
-	for i, field := range results[0] {
-		column := aws.ToString(field.Field)
-		if column == "@ptr" {
-			continue
-		}
-		index[i] = len(columns)
-		columns = append(columns, column)
+	columns := unionColumns(results)
+	rawRows := rawResultRows(results, columns)
+	columnTypes := make([]columnType, len(columns))
+	for j := range columns {
+		columnTypes[j] = inferColumnType(rawRows, j)
+	}
+	r := &cloudWatchLogsInsightsRows{
+		columns:     columns,
+		columnTypes: columnTypes,
+		rows:        make([][]driver.Value, 0, len(rawRows)),
+		index:       0,
 	}
+	r.appendRawRows(rawRows)
+	return r
+}
 
-	rows := make([][]driver.Value, len(results))
+// rawResultRows returns the values of the columns for each result row, nil for the missing fields.
+// The fields that are not in columns, such as @ptr, are dropped.
+func rawResultRows(results [][]types.ResultField, columns []string) [][]*string {
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[column] = i
+	}
+	rawRows := make([][]*string, len(results))
 	for i := 0; i < len(results); i++ {
-		rowValues := make([]driver.Value, len(columns))
-		for j, field := range results[i] {
+		rowValues := make([]*string, len(columns))
+		for _, field := range results[i] {
+			j, ok := index[aws.ToString(field.Field)]
+			if !ok {
+				continue
+			}
+			rowValues[j] = aws.String(aws.ToString(field.Value))
+		}
+		rawRows[i] = rowValues
+	}
+	return rawRows
+}
+
+func (r *cloudWatchLogsInsightsRows) appendRawRows(rawRows [][]*string) {
+	for _, rawRow := range rawRows {
+		rowValues := make([]driver.Value, len(r.columns))
+		for j, str := range rawRow {
+			rowValues[j] = r.columnTypes[j].convert(str)
+		}
+		r.rows = append(r.rows, rowValues)
+	}
+}
+
+// unionColumns returns the fields of all result rows except @ptr.
+// Logs Insights omits empty fields per record, so a field first seen in a later row is
+// inserted right after the field that precedes it in that row, to keep the query field order.
+func unionColumns(results [][]types.ResultField) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		pos := 0
+		for _, field := range result {
 			name := aws.ToString(field.Field)
 			if name == "@ptr" {
 				continue
 			}
-			str := aws.ToString(field.Value)
-			if name == "@timestamp" {
-				if t, err := time.Parse("2006-01-02 15:04:05", str); err == nil {
-					rowValues[index[j]] = driver.Value(t)
-					continue
+			if seen[name] {
+				for i, column := range columns {
+					if column == name {
+						pos = i + 1
+						break
+					}
 				}
+				continue
 			}
-			rowValues[index[j]] = driver.Value(str)
+			seen[name] = true
+			columns = append(columns, "")
+			copy(columns[pos+1:], columns[pos:])
+			columns[pos] = name
+			pos++
 		}
-		rows[i] = rowValues
 	}
+	return columns
+}
 
-	return &cloudWatchLogsInsightsRows{
-		columns: columns,
-		rows:    rows,
-		index:   0,
+type columnType struct {
+	databaseTypeName string
+	scanType         reflect.Type
+	parse            func(string) (driver.Value, bool)
+}
+
+var (
+	integerColumnType = columnType{
+		databaseTypeName: "INTEGER",
+		scanType:         reflect.TypeOf(int64(0)),
+		parse: func(s string) (driver.Value, bool) {
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || strconv.FormatInt(i, 10) != s {
+				return nil, false
+			}
+			return i, true
+		},
+	}
+	floatColumnType = columnType{
+		databaseTypeName: "FLOAT",
+		scanType:         reflect.TypeOf(float64(0)),
+		parse: func(s string) (driver.Value, bool) {
+			if !floatPattern.MatchString(s) {
+				return nil, false
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, false
+			}
+			return f, true
+		},
+	}
+	boolColumnType = columnType{
+		databaseTypeName: "BOOLEAN",
+		scanType:         reflect.TypeOf(false),
+		parse: func(s string) (driver.Value, bool) {
+			switch s {
+			case "true":
+				return true, true
+			case "false":
+				return false, true
+			}
+			return nil, false
+		},
+	}
+	timestampColumnType = columnType{
+		databaseTypeName: "TIMESTAMP",
+		scanType:         reflect.TypeOf(time.Time{}),
+		parse: func(s string) (driver.Value, bool) {
+			if len(s) < len("2006-01-02 15:04:05") {
+				return nil, false
+			}
+			// time.Parse accepts fractional seconds even though the layout does not have them.
+			t, err := time.Parse("2006-01-02 15:04:05", s)
+			if err != nil {
+				return nil, false
+			}
+			return t, true
+		},
+	}
+	stringColumnType = columnType{
+		databaseTypeName: "STRING",
+		scanType:         reflect.TypeOf(""),
+		parse: func(s string) (driver.Value, bool) {
+			return s, true
+		},
+	}
+)
+
+// floatPattern rejects the forms that strconv.ParseFloat accepts but Logs Insights does not return as numbers, such as "inf", "0x1p-2" or "007".
+var floatPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+var inferableColumnTypes = []columnType{
+	integerColumnType,
+	floatColumnType,
+	boolColumnType,
+	timestampColumnType,
+}
+
+// inferColumnType returns the narrowest column type that can parse every non-empty value of the column.
+func inferColumnType(rows [][]*string, j int) columnType {
+	var hasValue bool
+	for _, row := range rows {
+		if coalesce(row[j]) != "" {
+			hasValue = true
+			break
+		}
+	}
+	if !hasValue {
+		return stringColumnType
+	}
+	for _, ct := range inferableColumnTypes {
+		if ct.parseAll(rows, j) {
+			return ct
+		}
+	}
+	return stringColumnType
+}
+
+func (ct columnType) parseAll(rows [][]*string, j int) bool {
+	for _, row := range rows {
+		if coalesce(row[j]) == "" {
+			continue
+		}
+		if _, ok := ct.parse(*row[j]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// convert returns nil for the field that the row does not contain.
+func (ct columnType) convert(s *string) driver.Value {
+	if s == nil {
+		return nil
+	}
+	if *s == "" && ct.databaseTypeName != stringColumnType.databaseTypeName {
+		return nil
+	}
+	v, ok := ct.parse(*s)
+	if !ok {
+		return *s
 	}
+	return v
 }
@@ -0,0 +1,51 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+func resultRow(kv ...string) []types.ResultField {
+	fields := make([]types.ResultField, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		fields = append(fields, types.ResultField{
+			Field: aws.String(kv[i]),
+			Value: aws.String(kv[i+1]),
+		})
+	}
+	return fields
+}
+
+func TestNewRows__UnionColumns(t *testing.T) {
+	rows := newRows([][]types.ResultField{
+		resultRow("level", "info", "msg", "a", "@ptr", "1"),
+		resultRow("level", "error", "code", "500", "msg", "b", "@ptr", "2"),
+		resultRow("msg", "", "user", "alice", "@ptr", "3"),
+	})
+	expectedColumns := []string{"level", "code", "msg", "user"}
+	if !reflect.DeepEqual(rows.Columns(), expectedColumns) {
+		t.Fatalf("expected %v, got %v", expectedColumns, rows.Columns())
+	}
+	expectedRows := [][]driver.Value{
+		{"info", nil, "a", nil},
+		{"error", int64(500), "b", nil},
+		{nil, nil, "", "alice"},
+	}
+	for i, expected := range expectedRows {
+		dest := make([]driver.Value, len(expectedColumns))
+		if err := rows.Next(dest); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(dest, expected) {
+			t.Errorf("row %d: expected %#v, got %#v", i, expected, dest)
+		}
+	}
+	if err := rows.Next(make([]driver.Value, len(expectedColumns))); err != io.EOF {
+		t.Fatal("expected io.EOF, got", err)
+	}
+}
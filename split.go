@@ -0,0 +1,128 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// maxQueryResults is the maximum number of rows that a single Logs Insights query can return.
+const maxQueryResults = 10000
+
+type timeWindow struct {
+	start int64 // epoch seconds
+	end   int64 // epoch seconds
+}
+
+// splitWindows splits [start, end] into windows of interval seconds.
+// Adjacent windows share the boundary second, the duplicated records are removed by @ptr.
+func splitWindows(start, end, interval int64) []timeWindow {
+	if interval <= 0 || end-start <= interval {
+		return []timeWindow{{start: start, end: end}}
+	}
+	windows := make([]timeWindow, 0, (end-start)/interval+1)
+	for s := start; s < end; s += interval {
+		e := s + interval
+		if e > end {
+			e = end
+		}
+		windows = append(windows, timeWindow{start: s, end: e})
+	}
+	return windows
+}
+
+var sortTimestampAscPattern = regexp.MustCompile(`(?i)\|\s*sort\s+@timestamp\s+asc\b`)
+
+// splitQuery runs the query for each time window of opts.splitInterval and merges the results
+// with the sort and limit commands of the query applied again.
+// A window that hits maxQueryResults is bisected until it fits, and ErrResultsTruncated is returned
+// if a window of 1 second still hits it.
+func (conn *cloudwatchLogsInsightsConn) splitQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, opts *queryOptions) ([][]types.ResultField, error) {
+	order, err := parseResultOrder(aws.ToString(params.QueryString))
+	if err != nil {
+		return nil, err
+	}
+	windows := splitWindows(aws.ToInt64(params.StartTime), aws.ToInt64(params.EndTime), int64(opts.splitInterval.Seconds()))
+	ascending := sortTimestampAscPattern.MatchString(aws.ToString(params.QueryString))
+	if !ascending {
+		// Logs Insights returns the newest records first by default.
+		for i, j := 0, len(windows)-1; i < j; i, j = i+1, j-1 {
+			windows[i], windows[j] = windows[j], windows[i]
+		}
+	}
+	conn.logger.DebugContext(ctx, "split query", slog.Int("windows", len(windows)), slog.Int("concurrency", opts.concurrencyOr(1)))
+
+	windowResults := make([][][]types.ResultField, len(windows))
+	err = runConcurrently(ctx, len(windows), opts.concurrencyOr(1), func(ctx context.Context, i int) error {
+		var err error
+		windowResults[i], err = conn.queryWindow(ctx, params, windows[i], ascending)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var merged [][]types.ResultField
+	seen := make(map[string]bool)
+	for _, results := range windowResults {
+		for _, result := range results {
+			if ptr := resultFieldValue(result, "@ptr"); ptr != nil {
+				if seen[*ptr] {
+					continue
+				}
+				seen[*ptr] = true
+			}
+			merged = append(merged, result)
+		}
+	}
+	return order.apply(merged, opts.limit), nil
+}
+
+func (conn *cloudwatchLogsInsightsConn) queryWindow(ctx context.Context, params *cloudwatchlogs.StartQueryInput, w timeWindow, ascending bool) ([][]types.ResultField, error) {
+	windowParams := *params
+	windowParams.StartTime = aws.Int64(w.start)
+	windowParams.EndTime = aws.Int64(w.end)
+	windowParams.Limit = aws.Int32(maxQueryResults)
+	output, err := conn.startQuery(ctx, &windowParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Results) < maxQueryResults {
+		return output.Results, nil
+	}
+	if w.end-w.start <= 1 {
+		return nil, fmt.Errorf("window %s - %s has %d or more records: %w",
+			time.Unix(w.start, 0).UTC().Format(time.RFC3339), time.Unix(w.end, 0).UTC().Format(time.RFC3339), maxQueryResults, ErrResultsTruncated)
+	}
+	mid := w.start + (w.end-w.start)/2
+	halves := []timeWindow{{start: w.start, end: mid}, {start: mid, end: w.end}}
+	if !ascending {
+		halves[0], halves[1] = halves[1], halves[0]
+	}
+	conn.logger.DebugContext(ctx, "window hits max results, bisect",
+		slog.Time(LogKeyStartTime, time.Unix(w.start, 0)), slog.Time(LogKeyEndTime, time.Unix(w.end, 0)), slog.Time("mid", time.Unix(mid, 0)))
+	var results [][]types.ResultField
+	for _, half := range halves {
+		r, err := conn.queryWindow(ctx, params, half, ascending)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r...)
+	}
+	return results, nil
+}
+
+func resultFieldValue(result []types.ResultField, name string) *string {
+	for _, field := range result {
+		if aws.ToString(field.Field) == name {
+			return field.Value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// QueryStatistics is the statistics of a Logs Insights query.
+type QueryStatistics struct {
+	QueryID        string
+	RecordsMatched float64
+	RecordsScanned float64
+	BytesScanned   float64
+	ElapsedTime    time.Duration
+}
+
+func newQueryStatistics(queryID string, stats *types.QueryStatistics, elapsed time.Duration) QueryStatistics {
+	s := QueryStatistics{
+		QueryID:     queryID,
+		ElapsedTime: elapsed,
+	}
+	if stats != nil {
+		s.RecordsMatched = stats.RecordsMatched
+		s.RecordsScanned = stats.RecordsScanned
+		s.BytesScanned = stats.BytesScanned
+	}
+	return s
+}
+
+// QueryStatisticsProvider is implemented by the driver.Conn and the driver.Rows of this driver.
+// A query may run several Logs Insights queries, for example split_interval or more than 50 log groups,
+// so the statistics of each query are returned.
+//
+// The driver.Conn returns the statistics of the last query, it can be reached by sql.Conn.Raw.
+//
+//	conn.Raw(func(driverConn any) error {
+//		stats := driverConn.(cloudwatchlogsinsightsdriver.QueryStatisticsProvider).QueryStatistics()
+//		...
+//	})
+type QueryStatisticsProvider interface {
+	QueryStatistics() []QueryStatistics
+}
+
+// TotalQueryStatistics returns the sum of the statistics. QueryID is empty, and ElapsedTime is the longest one.
+func TotalQueryStatistics(stats []QueryStatistics) QueryStatistics {
+	var total QueryStatistics
+	for _, s := range stats {
+		total.RecordsMatched += s.RecordsMatched
+		total.RecordsScanned += s.RecordsScanned
+		total.BytesScanned += s.BytesScanned
+		if s.ElapsedTime > total.ElapsedTime {
+			total.ElapsedTime = s.ElapsedTime
+		}
+	}
+	return total
+}
+
+type statisticsCollectorKey struct{}
+
+// WithQueryStatisticsCollector returns the context that calls collector with the statistics of each Logs Insights query
+// executed by db.QueryContext with the context.
+func WithQueryStatisticsCollector(ctx context.Context, collector func(QueryStatistics)) context.Context {
+	if parent, ok := ctx.Value(statisticsCollectorKey{}).(func(QueryStatistics)); ok {
+		child := collector
+		collector = func(s QueryStatistics) {
+			parent(s)
+			child(s)
+		}
+	}
+	return context.WithValue(ctx, statisticsCollectorKey{}, collector)
+}
+
+// statisticsRecorder records the statistics of the Logs Insights queries executed by one QueryContext.
+type statisticsRecorder struct {
+	mu    sync.Mutex
+	stats []QueryStatistics
+}
+
+type statisticsRecorderKey struct{}
+
+func withStatisticsRecorder(ctx context.Context) (context.Context, *statisticsRecorder) {
+	rec := &statisticsRecorder{}
+	return context.WithValue(ctx, statisticsRecorderKey{}, rec), rec
+}
+
+func (rec *statisticsRecorder) list() []QueryStatistics {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]QueryStatistics{}, rec.stats...)
+}
+
+func recordQueryStatistics(ctx context.Context, s QueryStatistics) {
+	if rec, ok := ctx.Value(statisticsRecorderKey{}).(*statisticsRecorder); ok {
+		rec.mu.Lock()
+		rec.stats = append(rec.stats, s)
+		rec.mu.Unlock()
+	}
+	if collector, ok := ctx.Value(statisticsCollectorKey{}).(func(QueryStatistics)); ok {
+		collector(s)
+	}
+}
@@ -0,0 +1,288 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// statsQuery is a query with a `stats` command that can be executed as partial queries and re-aggregated.
+type statsQuery struct {
+	prefix     []string // commands before stats
+	aggregates []statsAggregate
+	groups     []statsGroup
+	sortKeys   []sortKey
+	limit      int // 0 means no limit
+}
+
+type statsAggregate struct {
+	function string // count, sum, min, max or avg
+	arg      string
+	column   string // result column name
+}
+
+type statsGroup struct {
+	expr   string // group expression with alias, as written
+	column string // result column name
+}
+
+// parseStatsQuery parses the query with a `stats` command.
+// It returns nil if the query has no stats command, and wraps ErrNotMergeable if the partial results can not be merged.
+func parseStatsQuery(query string) (*statsQuery, error) {
+	commands, err := splitCommands(query)
+	if err != nil {
+		return nil, err
+	}
+	statsIndex := -1
+	for i, cmd := range commands {
+		if cmd.name != "stats" {
+			continue
+		}
+		if statsIndex >= 0 {
+			return nil, fmt.Errorf("multiple stats commands are %w", ErrNotMergeable)
+		}
+		statsIndex = i
+	}
+	if statsIndex < 0 {
+		return nil, nil
+	}
+	q := &statsQuery{}
+	for _, cmd := range commands[:statsIndex] {
+		// the commands before stats are copied into each partial query,
+		// so the commands over the whole time range and log groups can not be partitioned.
+		switch cmd.name {
+		case "limit", "dedup":
+			return nil, fmt.Errorf("%s command before stats is %w", cmd.name, ErrNotMergeable)
+		}
+		q.prefix = append(q.prefix, cmd.text)
+	}
+	if err := q.parseStats(commands[statsIndex].text); err != nil {
+		return nil, err
+	}
+	for _, cmd := range commands[statsIndex+1:] {
+		switch cmd.name {
+		case "sort":
+			if q.sortKeys, err = parseSort(cmd.text); err != nil {
+				return nil, err
+			}
+		case "limit":
+			if q.limit, err = parseLimit(cmd.text); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%s command after stats is %w", cmd.name, ErrNotMergeable)
+		}
+	}
+	return q, nil
+}
+
+func (q *statsQuery) parseStats(text string) error {
+	body := strings.TrimSpace(text[len("stats"):])
+	aggBody, groupBody := body, ""
+	if i := indexTopLevelWord(body, "by"); i >= 0 {
+		aggBody, groupBody = body[:i], body[i+len("by"):]
+	}
+	aggs, err := splitTopLevel(aggBody, ',')
+	if err != nil {
+		return err
+	}
+	for _, agg := range aggs {
+		expr, alias := splitAlias(agg)
+		open := strings.IndexByte(expr, '(')
+		if open <= 0 || !strings.HasSuffix(expr, ")") {
+			return fmt.Errorf("stats expression %q is %w", expr, ErrNotMergeable)
+		}
+		function := strings.ToLower(strings.TrimSpace(expr[:open]))
+		switch function {
+		case "count", "sum", "min", "max", "avg":
+		default:
+			return fmt.Errorf("stats function %s is %w", function, ErrNotMergeable)
+		}
+		column := alias
+		if column == "" {
+			column = expr
+		}
+		q.aggregates = append(q.aggregates, statsAggregate{
+			function: function,
+			arg:      strings.TrimSpace(expr[open+1 : len(expr)-1]),
+			column:   column,
+		})
+	}
+	if strings.TrimSpace(groupBody) == "" {
+		return nil
+	}
+	groups, err := splitTopLevel(groupBody, ',')
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		expr, alias := splitAlias(group)
+		column := alias
+		if column == "" {
+			column = unquoteField(expr)
+		}
+		q.groups = append(q.groups, statsGroup{
+			expr:   strings.TrimSpace(group),
+			column: column,
+		})
+	}
+	return nil
+}
+
+func partialColumn(i int, suffix string) string {
+	return fmt.Sprintf("__agg%d%s", i, suffix)
+}
+
+// partialQuery returns the query for a partition. avg is rewritten to sum and count,
+// and the sort and limit commands after stats are removed to fetch all groups of the partition.
+func (q *statsQuery) partialQuery() string {
+	aggs := make([]string, 0, len(q.aggregates))
+	for i, agg := range q.aggregates {
+		switch agg.function {
+		case "avg":
+			aggs = append(aggs,
+				fmt.Sprintf("sum(%s) as %s", agg.arg, partialColumn(i, "_sum")),
+				fmt.Sprintf("count(%s) as %s", agg.arg, partialColumn(i, "_count")),
+			)
+		default:
+			aggs = append(aggs, fmt.Sprintf("%s(%s) as %s", agg.function, agg.arg, partialColumn(i, "")))
+		}
+	}
+	stats := "stats " + strings.Join(aggs, ", ")
+	if len(q.groups) > 0 {
+		groups := make([]string, 0, len(q.groups))
+		for _, g := range q.groups {
+			groups = append(groups, g.expr)
+		}
+		stats += " by " + strings.Join(groups, ", ")
+	}
+	return strings.Join(append(append([]string{}, q.prefix...), stats), "\n| ")
+}
+
+type statsBucket struct {
+	groupValues []*string
+	sums        []float64
+	counts      []float64
+	present     []bool
+}
+
+// merge re-aggregates the partial results into the result of the original query.
+func (q *statsQuery) merge(partials [][][]types.ResultField) [][]types.ResultField {
+	var buckets []*statsBucket
+	index := make(map[string]*statsBucket)
+	for _, results := range partials {
+		for _, result := range results {
+			groupValues := make([]*string, len(q.groups))
+			var key strings.Builder
+			for j, g := range q.groups {
+				groupValues[j] = resultFieldValue(result, g.column)
+				if groupValues[j] != nil {
+					key.WriteString(*groupValues[j])
+				}
+				key.WriteByte(0)
+			}
+			b, ok := index[key.String()]
+			if !ok {
+				b = &statsBucket{
+					groupValues: groupValues,
+					sums:        make([]float64, len(q.aggregates)),
+					counts:      make([]float64, len(q.aggregates)),
+					present:     make([]bool, len(q.aggregates)),
+				}
+				index[key.String()] = b
+				buckets = append(buckets, b)
+			}
+			for i, agg := range q.aggregates {
+				b.add(i, agg.function, result)
+			}
+		}
+	}
+
+	merged := make([][]types.ResultField, 0, len(buckets))
+	for _, b := range buckets {
+		row := make([]types.ResultField, 0, len(q.groups)+len(q.aggregates))
+		for j, g := range q.groups {
+			if b.groupValues[j] == nil {
+				continue
+			}
+			row = append(row, types.ResultField{Field: aws.String(g.column), Value: b.groupValues[j]})
+		}
+		for i, agg := range q.aggregates {
+			if v := b.value(i, agg.function); v != nil {
+				row = append(row, types.ResultField{Field: aws.String(agg.column), Value: v})
+			}
+		}
+		merged = append(merged, row)
+	}
+	sortResults(merged, q.sortKeys)
+	if q.limit > 0 && len(merged) > q.limit {
+		merged = merged[:q.limit]
+	}
+	return merged
+}
+
+func (b *statsBucket) add(i int, function string, result []types.ResultField) {
+	if function == "avg" {
+		sum, ok1 := parseFloatField(result, partialColumn(i, "_sum"))
+		count, ok2 := parseFloatField(result, partialColumn(i, "_count"))
+		if ok1 && ok2 {
+			b.sums[i] += sum
+			b.counts[i] += count
+			b.present[i] = true
+		}
+		return
+	}
+	v, ok := parseFloatField(result, partialColumn(i, ""))
+	if !ok {
+		return
+	}
+	switch function {
+	case "count", "sum":
+		b.sums[i] += v
+	case "min":
+		if !b.present[i] || v < b.sums[i] {
+			b.sums[i] = v
+		}
+	case "max":
+		if !b.present[i] || v > b.sums[i] {
+			b.sums[i] = v
+		}
+	}
+	b.present[i] = true
+}
+
+func (b *statsBucket) value(i int, function string) *string {
+	switch function {
+	case "count":
+		return aws.String(strconv.FormatFloat(b.sums[i], 'f', 0, 64))
+	case "avg":
+		if b.counts[i] == 0 {
+			return nil
+		}
+		return aws.String(formatFloat(b.sums[i] / b.counts[i]))
+	}
+	if !b.present[i] {
+		return nil
+	}
+	return aws.String(formatFloat(b.sums[i]))
+}
+
+func parseFloatField(result []types.ResultField, name string) (float64, bool) {
+	v := resultFieldValue(result, name)
+	if v == nil {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(*v, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
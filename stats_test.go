@@ -0,0 +1,90 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+func TestParseStatsQuery__PartialQuery(t *testing.T) {
+	sq, err := parseStatsQuery("fields @message # comment | stats\n| filter status >= 500 | stats count(*) as errors, avg(duration), max(duration) by path, bin(1h) | sort errors desc | limit 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "fields @message\n| filter status >= 500\n| stats count(*) as __agg0, sum(duration) as __agg1_sum, count(duration) as __agg1_count, max(duration) as __agg2 by path, bin(1h)"
+	if actual := sq.partialQuery(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if !reflect.DeepEqual(sq.sortKeys, []sortKey{{column: "errors", desc: true}}) {
+		t.Errorf("unexpected sort keys: %v", sq.sortKeys)
+	}
+	if sq.limit != 2 {
+		t.Errorf("unexpected limit: %d", sq.limit)
+	}
+}
+
+func TestParseStatsQuery__NotMergeable(t *testing.T) {
+	queries := []string{
+		"stats pct(duration, 99) by path",
+		"stats count_distinct(user)",
+		"stats count(*) by path | filter `count(*)` > 10",
+		"stats count(*) as c by path | stats avg(c)",
+		"fields duration | limit 100 | stats avg(duration)",
+		"sort duration desc | limit 10 | stats sum(duration)",
+		"dedup user | stats count(*) by path",
+	}
+	for _, query := range queries {
+		_, err := parseStatsQuery(query)
+		if !errors.Is(err, ErrNotMergeable) {
+			t.Errorf("%q: expected ErrNotMergeable, got %v", query, err)
+		}
+	}
+}
+
+func TestParseStatsQuery__NoStats(t *testing.T) {
+	sq, err := parseStatsQuery(`fields @message | filter @message like "stats"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sq != nil {
+		t.Fatal("expected nil")
+	}
+}
+
+func TestStatsQuery__Merge(t *testing.T) {
+	sq, err := parseStatsQuery("stats count(*) as errors, avg(duration), min(duration), sum(bytes) by path | sort errors desc | limit 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	partials := [][][]types.ResultField{
+		{
+			resultRow("path", "/a", "__agg0", "3", "__agg1_sum", "30", "__agg1_count", "3", "__agg2", "5", "__agg3", "100"),
+			resultRow("path", "/b", "__agg0", "1", "__agg1_sum", "7", "__agg1_count", "1", "__agg2", "7", "__agg3", "10"),
+			resultRow("path", "/c", "__agg0", "2", "__agg1_sum", "2", "__agg1_count", "2", "__agg2", "1", "__agg3", "1"),
+		},
+		{
+			resultRow("path", "/b", "__agg0", "4", "__agg1_sum", "13", "__agg1_count", "4", "__agg2", "2", "__agg3", "20.5"),
+		},
+	}
+	expected := [][]types.ResultField{
+		resultRow("path", "/b", "errors", "5", "avg(duration)", "4", "min(duration)", "2", "sum(bytes)", "30.5"),
+		resultRow("path", "/a", "errors", "3", "avg(duration)", "10", "min(duration)", "5", "sum(bytes)", "100"),
+	}
+	actual := sq.merge(partials)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d rows, got %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if aws.ToString(actual[i][j].Field) != aws.ToString(expected[i][j].Field) || aws.ToString(actual[i][j].Value) != aws.ToString(expected[i][j].Value) {
+				t.Errorf("row %d field %d: expected %s=%s, got %s=%s", i, j,
+					aws.ToString(expected[i][j].Field), aws.ToString(expected[i][j].Value),
+					aws.ToString(actual[i][j].Field), aws.ToString(actual[i][j].Value),
+				)
+			}
+		}
+	}
+}
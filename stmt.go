@@ -0,0 +1,41 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+type cloudwatchLogsInsightsStmt struct {
+	conn  *cloudwatchLogsInsightsConn
+	query string
+}
+
+func (stmt *cloudwatchLogsInsightsStmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1, because named parameters such as start_time are mixed with bind parameters.
+func (stmt *cloudwatchLogsInsightsStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *cloudwatchLogsInsightsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("exec statment %w", ErrNotSupported)
+}
+
+func (stmt *cloudwatchLogsInsightsStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return nil, fmt.Errorf("exec statment %w", ErrNotSupported)
+}
+
+func (stmt *cloudwatchLogsInsightsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	namedArgs := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		namedArgs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return stmt.QueryContext(context.Background(), namedArgs)
+}
+
+func (stmt *cloudwatchLogsInsightsStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return stmt.conn.QueryContext(ctx, stmt.query, args)
+}
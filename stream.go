@@ -0,0 +1,271 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// streamingRows yields the rows of the running query as they appear in GetQueryResults responses.
+// The columns and the column types are decided by the first batch of the results,
+// the fields that are not in the first batch are dropped with a warning.
+type streamingRows struct {
+	*cloudWatchLogsInsightsRows
+	conn    *cloudwatchLogsInsightsConn
+	ctx     context.Context
+	cancel  context.CancelFunc
+	rec     *statisticsRecorder
+	queryID *string
+	params  *cloudwatchlogs.StartQueryInput
+	qt      *queryTelemetry
+
+	queryStart   time.Time
+	pollStrategy PollStrategy
+	pollState    PollState
+	seen         map[string]bool
+	dropped      map[string]bool        // the fields dropped because they are not in the columns
+	statistics   *types.QueryStatistics // the last seen statistics
+	recorded     bool
+	err          error
+	finished     bool
+	closed       bool
+}
+
+// streamQuery starts the query and returns the rows after the first batch of the results is found.
+// cancel is called when the rows are closed.
+func (conn *cloudwatchLogsInsightsConn) streamQuery(ctx context.Context, cancel context.CancelFunc, query string, opts *queryOptions) (driver.Rows, error) {
+	if len(opts.logGroupNames) > maxLogGroupsPerQuery || opts.splitInterval > 0 || opts.partitionInterval > 0 || opts.partitionLogGroups > 0 {
+		cancel()
+		return nil, fmt.Errorf("stream can not be used with split, partitioned or more than %d log groups queries", maxLogGroupsPerQuery)
+	}
+	if err := checkStreamable(query, opts); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := conn.connector.limiter.acquire(ctx, opts.priority); err != nil {
+		cancel()
+		return nil, err
+	}
+	params := newStartQueryInput(query, opts)
+	ctx, qt := conn.connector.telemetry.startQuery(ctx, params)
+	queryID, err := conn.startQueryNoWait(ctx, params)
+	if err != nil {
+		qt.end(ctx, err)
+		conn.connector.limiter.release()
+		cancel()
+		return nil, err
+	}
+	qt.setQueryID(queryID)
+	ctx, cancelWait := context.WithTimeout(ctx, conn.waitTimeout(ctx))
+	cancelQuery := cancel
+	cancel = func() {
+		cancelWait()
+		cancelQuery()
+	}
+	ctx, rec := withStatisticsRecorder(ctx)
+	ctx = withScanBudget(ctx, opts)
+	pollStrategy := conn.cfg.PollStrategy
+	if pollStrategy == nil {
+		pollStrategy = FixedPollStrategy{Interval: conn.cfg.Polling}
+	}
+	r := &streamingRows{
+		conn:         conn,
+		ctx:          ctx,
+		cancel:       cancel,
+		rec:          rec,
+		queryID:      queryID,
+		params:       params,
+		qt:           qt,
+		queryStart:   time.Now(),
+		pollStrategy: pollStrategy,
+		seen:         make(map[string]bool),
+		dropped:      make(map[string]bool),
+	}
+	for r.cloudWatchLogsInsightsRows == nil {
+		results, err := r.poll()
+		if err != nil {
+			r.closeWithError(err)
+			return nil, err
+		}
+		if len(results) > 0 || r.finished {
+			r.cloudWatchLogsInsightsRows = newRows(results)
+		}
+	}
+	return r, nil
+}
+
+func (r *streamingRows) Next(dest []driver.Value) error {
+	for r.index >= len(r.rows) {
+		if r.finished {
+			return io.EOF
+		}
+		results, err := r.poll()
+		if err != nil {
+			r.err = err
+			return err
+		}
+		r.rows = r.rows[r.index:]
+		r.index = 0
+		r.warnDroppedFields(results)
+		r.appendRawRows(rawResultRows(results, r.columns))
+	}
+	return r.cloudWatchLogsInsightsRows.Next(dest)
+}
+
+// checkStreamable returns an error if the rows of the running query may not be in the final results,
+// because the sort and limit of the query are applied to the records matched so far.
+func checkStreamable(query string, opts *queryOptions) error {
+	if opts.limit != nil {
+		return fmt.Errorf("stream can not be used with limit")
+	}
+	commands, err := splitCommands(query)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range commands {
+		switch cmd.name {
+		case "sort", "limit":
+			return fmt.Errorf("stream can not be used with the %s command", cmd.name)
+		}
+	}
+	return nil
+}
+
+// warnDroppedFields logs the fields that are not in the columns once for each field.
+func (r *streamingRows) warnDroppedFields(results [][]types.ResultField) {
+	columns := make(map[string]bool, len(r.columns))
+	for _, column := range r.columns {
+		columns[column] = true
+	}
+	for _, result := range results {
+		for _, field := range result {
+			name := coalesce(field.Field)
+			if name == "@ptr" || columns[name] || r.dropped[name] {
+				continue
+			}
+			r.dropped[name] = true
+			r.conn.logger.WarnContext(r.ctx, "field is not in the columns of the first batch, dropped",
+				slog.String(LogKeyQueryID, coalesce(r.queryID)), slog.String("field", name))
+		}
+	}
+}
+
+// Close stops the query if it is still running.
+func (r *streamingRows) Close() error {
+	r.closeWithError(r.err)
+	return nil
+}
+
+// closeWithError closes the rows, err is the error of the query that is reported to the span.
+func (r *streamingRows) closeWithError(err error) {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if !r.finished {
+		r.conn.stopQuery(r.ctx, r.queryID)
+	}
+	r.recordStatistics()
+	r.qt.end(r.ctx, err)
+	r.conn.connector.limiter.release()
+	r.conn.statistics = r.rec.list()
+	r.cancel()
+}
+
+// recordStatistics records the last seen statistics once, when the query is finished or the rows are closed.
+func (r *streamingRows) recordStatistics() {
+	if r.recorded || r.pollState.Attempt == 0 {
+		return
+	}
+	r.recorded = true
+	recordQueryStatistics(r.ctx, newQueryStatistics(coalesce(r.queryID), r.statistics, time.Since(r.queryStart)))
+}
+
+// QueryStatistics implements QueryStatisticsProvider.
+func (r *streamingRows) QueryStatistics() []QueryStatistics {
+	return r.rec.list()
+}
+
+// poll waits for the polling interval, and returns the results that are not returned yet.
+// While the query is running, only the results with @ptr are returned, because the others can not be de-duplicated.
+func (r *streamingRows) poll() ([][]types.ResultField, error) {
+	logger := r.conn.logger.With(queryLogAttrs(r.queryID, r.params)...)
+	if r.pollState.Attempt > 0 {
+		r.pollState.Elapsed = time.Since(r.queryStart)
+		interval := r.pollStrategy.NextInterval(r.pollState)
+		r.pollState.PreviousInterval = interval
+		logger.DebugContext(r.ctx, "waiting for next results", slog.Duration(LogKeyDuration, r.pollState.Elapsed), slog.Duration("next_poll", interval))
+		timer := time.NewTimer(interval)
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+			return nil, r.ctx.Err()
+		case <-r.conn.aliveCh:
+			timer.Stop()
+			return nil, ErrConnClosed
+		case <-timer.C:
+		}
+	}
+	output, err := r.conn.getQueryResults(r.ctx, r.queryID)
+	if err != nil {
+		return nil, fmt.Errorf("get query results:%w", err)
+	}
+	r.qt.poll(r.ctx, output)
+	reportQueryProgress(r.ctx, coalesce(r.queryID), output, time.Since(r.queryStart))
+	r.pollState.Attempt++
+	r.pollState.PreviousStatistics = r.pollState.Statistics
+	r.pollState.Statistics = output.Statistics
+	r.statistics = output.Statistics
+	switch output.Status {
+	case types.QueryStatusComplete:
+		r.finished = true
+		r.recordStatistics()
+		logger.InfoContext(r.ctx, "query completed", statisticsLogAttrs(output.Status, output.Statistics, time.Since(r.queryStart))...)
+		return r.unseen(output.Results, true), nil
+	case types.QueryStatusFailed, types.QueryStatusCancelled, types.QueryStatusTimeout, types.QueryStatusUnknown:
+		r.finished = true
+		r.recordStatistics()
+		logger.WarnContext(r.ctx, "query is not completed", statisticsLogAttrs(output.Status, output.Statistics, time.Since(r.queryStart))...)
+		return nil, newQueryError(coalesce(r.queryID), output.Status, r.params)
+	}
+	if budget := getScanBudget(r.ctx); budget != nil {
+		if err := budget.check(coalesce(r.queryID), output.Statistics); err != nil {
+			r.conn.stopQuery(r.ctx, r.queryID)
+			r.finished = true
+			r.recordStatistics()
+			if budget.policy != ScanBudgetPolicyPartial {
+				return nil, err
+			}
+			logger.WarnContext(r.ctx, "scan budget exceeded, returns partial results", append(
+				statisticsLogAttrs(output.Status, output.Statistics, time.Since(r.queryStart)),
+				slog.Any("error", err),
+			)...)
+		}
+	}
+	return r.unseen(output.Results, false), nil
+}
+
+func (r *streamingRows) unseen(results [][]types.ResultField, complete bool) [][]types.ResultField {
+	var unseen [][]types.ResultField
+	for _, result := range results {
+		ptr := resultFieldValue(result, "@ptr")
+		if ptr == nil {
+			if complete {
+				unseen = append(unseen, result)
+			}
+			continue
+		}
+		if r.seen[*ptr] {
+			continue
+		}
+		r.seen[*ptr] = true
+		unseen = append(unseen, result)
+	}
+	return unseen
+}
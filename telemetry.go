@@ -0,0 +1,173 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+const instrumentationName = "github.com/mashiike/cloudwatch-logs-insights-driver"
+
+// The attribute keys of the spans and the metrics.
+const (
+	AttributeQueryID        = attribute.Key("aws.cloudwatch_logs_insights.query_id")
+	AttributeLogGroups      = attribute.Key("aws.log.group.names")
+	AttributeStartTime      = attribute.Key("aws.cloudwatch_logs_insights.start_time")
+	AttributeEndTime        = attribute.Key("aws.cloudwatch_logs_insights.end_time")
+	AttributeStatus         = attribute.Key("aws.cloudwatch_logs_insights.status")
+	AttributeRecordsMatched = attribute.Key("aws.cloudwatch_logs_insights.records_matched")
+	AttributeRecordsScanned = attribute.Key("aws.cloudwatch_logs_insights.records_scanned")
+	AttributeBytesScanned   = attribute.Key("aws.cloudwatch_logs_insights.bytes_scanned")
+)
+
+// telemetry is the OpenTelemetry instrumentation of a connector.
+// It is no-op when TracerProvider and MeterProvider are not configured.
+type telemetry struct {
+	tracer        trace.Tracer
+	queryDuration metric.Float64Histogram
+	queryPolls    metric.Int64Counter
+	queryFailures metric.Int64Counter
+	bytesScanned  metric.Float64Counter
+}
+
+func newTelemetry(cfg *CloudwatchLogsInsightsConfig) (*telemetry, error) {
+	tp := cfg.TracerProvider
+	if tp == nil {
+		tp = tracenoop.NewTracerProvider()
+	}
+	mp := cfg.MeterProvider
+	if mp == nil {
+		mp = metricnoop.NewMeterProvider()
+	}
+	meter := mp.Meter(instrumentationName)
+	t := &telemetry{
+		tracer: tp.Tracer(instrumentationName),
+	}
+	var err error
+	if t.queryDuration, err = meter.Float64Histogram("cloudwatch_logs_insights.query.duration",
+		metric.WithDescription("The duration of Logs Insights queries."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+	if t.queryPolls, err = meter.Int64Counter("cloudwatch_logs_insights.query.polls",
+		metric.WithDescription("The number of GetQueryResults calls."),
+		metric.WithUnit("{call}"),
+	); err != nil {
+		return nil, err
+	}
+	if t.queryFailures, err = meter.Int64Counter("cloudwatch_logs_insights.query.failures",
+		metric.WithDescription("The number of failed Logs Insights queries."),
+		metric.WithUnit("{query}"),
+	); err != nil {
+		return nil, err
+	}
+	if t.bytesScanned, err = meter.Float64Counter("cloudwatch_logs_insights.query.bytes_scanned",
+		metric.WithDescription("The bytes scanned by Logs Insights queries."),
+		metric.WithUnit("By"),
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// queryTelemetry is the span and the measurements of a Logs Insights query.
+type queryTelemetry struct {
+	t         *telemetry
+	span      trace.Span
+	start     time.Time
+	status    types.QueryStatus
+	statistic *types.QueryStatistics
+}
+
+type queryTelemetryKey struct{}
+
+// startQuery starts the span of the query. params is nil for an attached query.
+func (t *telemetry) startQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput) (context.Context, *queryTelemetry) {
+	var attrs []attribute.KeyValue
+	if params != nil {
+		var logGroups []string
+		switch {
+		case len(params.LogGroupIdentifiers) > 0:
+			logGroups = params.LogGroupIdentifiers
+		case len(params.LogGroupNames) > 0:
+			logGroups = params.LogGroupNames
+		case params.LogGroupName != nil:
+			logGroups = []string{*params.LogGroupName}
+		}
+		attrs = append(attrs,
+			AttributeLogGroups.StringSlice(logGroups),
+			AttributeStartTime.String(time.Unix(coalesce(params.StartTime), 0).UTC().Format(time.RFC3339)),
+			AttributeEndTime.String(time.Unix(coalesce(params.EndTime), 0).UTC().Format(time.RFC3339)),
+		)
+	}
+	ctx, span := t.tracer.Start(ctx, "cloudwatch_logs_insights.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	q := &queryTelemetry{t: t, span: span, start: time.Now()}
+	return context.WithValue(ctx, queryTelemetryKey{}, q), q
+}
+
+func queryTelemetryFromContext(ctx context.Context) *queryTelemetry {
+	q, _ := ctx.Value(queryTelemetryKey{}).(*queryTelemetry)
+	return q
+}
+
+func (q *queryTelemetry) setQueryID(queryID *string) {
+	if q == nil {
+		return
+	}
+	q.span.SetAttributes(AttributeQueryID.String(coalesce(queryID)))
+}
+
+// poll records an event of GetQueryResults.
+func (q *queryTelemetry) poll(ctx context.Context, output *cloudwatchlogs.GetQueryResultsOutput) {
+	if q == nil {
+		return
+	}
+	q.status = output.Status
+	q.statistic = output.Statistics
+	attrs := []attribute.KeyValue{AttributeStatus.String(string(output.Status))}
+	q.t.queryPolls.Add(ctx, 1)
+	q.span.AddEvent("poll", trace.WithAttributes(append(attrs, statisticsAttributes(output.Statistics)...)...))
+}
+
+// end ends the span, and records the metrics of the query.
+func (q *queryTelemetry) end(ctx context.Context, err error) {
+	if q == nil {
+		return
+	}
+	status := attribute.NewSet(AttributeStatus.String(string(q.status)))
+	q.t.queryDuration.Record(ctx, time.Since(q.start).Seconds(), metric.WithAttributeSet(status))
+	if q.statistic != nil {
+		q.t.bytesScanned.Add(ctx, q.statistic.BytesScanned, metric.WithAttributeSet(status))
+	}
+	q.span.SetAttributes(AttributeStatus.String(string(q.status)))
+	q.span.SetAttributes(statisticsAttributes(q.statistic)...)
+	if err != nil {
+		q.t.queryFailures.Add(ctx, 1, metric.WithAttributeSet(status))
+		q.span.RecordError(err)
+		q.span.SetStatus(codes.Error, err.Error())
+	}
+	q.span.End()
+}
+
+func statisticsAttributes(stats *types.QueryStatistics) []attribute.KeyValue {
+	if stats == nil {
+		return nil
+	}
+	return []attribute.KeyValue{
+		AttributeRecordsMatched.Float64(stats.RecordsMatched),
+		AttributeRecordsScanned.Float64(stats.RecordsScanned),
+		AttributeBytesScanned.Float64(stats.BytesScanned),
+	}
+}
@@ -0,0 +1,133 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryContext__WithMock__Telemetry(t *testing.T) {
+	mockClients["telemetry"] = &mockCloudWatchLogsClient{
+		StartQueryFunc: func(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+			return &cloudwatchlogs.StartQueryOutput{
+				QueryId: aws.String(*params.QueryString),
+			}, nil
+		},
+		GetQueryResultsFunc: func(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+			status := types.QueryStatusComplete
+			if *params.QueryId == "failed" {
+				status = types.QueryStatusFailed
+			} else if mockClients["telemetry"].GetQueryResultsCallCount == 1 {
+				status = types.QueryStatusRunning
+			}
+			return &cloudwatchlogs.GetQueryResultsOutput{
+				Status: status,
+				Statistics: &types.QueryStatistics{
+					RecordsMatched: 1,
+					RecordsScanned: 10,
+					BytesScanned:   100,
+				},
+			}, nil
+		},
+	}
+	connector, err := (&cloudwatchLogsInsightsDriver{}).OpenConnector("cloudwatch://?client=mock&mock=telemetry&log_group_name=test-log-group&polling=1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	cfg := connector.(*cloudwatchLogsInsightsConnector).cfg
+	cfg.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	cfg.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "completed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := db.QueryContext(ctx, "failed"); !errors.Is(err, ErrQueryFailed) {
+		t.Fatal("unexpected error:", err)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("unexpected spans: %d", len(ended))
+	}
+	completed := ended[0]
+	attrs := make(map[string]any)
+	for _, kv := range completed.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	if attrs[string(AttributeQueryID)] != "completed" || attrs[string(AttributeStatus)] != "Complete" || attrs[string(AttributeBytesScanned)] != float64(100) {
+		t.Error("unexpected attributes:", attrs)
+	}
+	if logGroups, ok := attrs[string(AttributeLogGroups)].([]string); !ok || len(logGroups) != 1 || logGroups[0] != "test-log-group" {
+		t.Error("unexpected log groups:", attrs[string(AttributeLogGroups)])
+	}
+	for _, key := range []string{string(AttributeStartTime), string(AttributeEndTime)} {
+		if _, ok := attrs[key]; !ok {
+			t.Errorf("%s is not in the attributes: %v", key, attrs)
+		}
+	}
+	if n := len(completed.Events()); n != 2 {
+		t.Errorf("unexpected poll events: %d", n)
+	}
+	if completed.Status().Code == codes.Error {
+		t.Error("unexpected span status:", completed.Status())
+	}
+	if failed := ended[1]; failed.Status().Code != codes.Error {
+		t.Error("unexpected span status:", failed.Status())
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	if duration, ok := metrics["cloudwatch_logs_insights.query.duration"].(metricdata.Histogram[float64]); !ok || sumHistogramCount(duration) != 2 {
+		t.Error("unexpected query duration:", metrics["cloudwatch_logs_insights.query.duration"])
+	}
+	if polls, ok := metrics["cloudwatch_logs_insights.query.polls"].(metricdata.Sum[int64]); !ok || sumDataPoints(polls.DataPoints) != 3 {
+		t.Error("unexpected polls:", metrics["cloudwatch_logs_insights.query.polls"])
+	}
+	if failures, ok := metrics["cloudwatch_logs_insights.query.failures"].(metricdata.Sum[int64]); !ok || sumDataPoints(failures.DataPoints) != 1 {
+		t.Error("unexpected failures:", metrics["cloudwatch_logs_insights.query.failures"])
+	}
+	if scanned, ok := metrics["cloudwatch_logs_insights.query.bytes_scanned"].(metricdata.Sum[float64]); !ok || sumDataPoints(scanned.DataPoints) != 200 {
+		t.Error("unexpected bytes scanned:", metrics["cloudwatch_logs_insights.query.bytes_scanned"])
+	}
+}
+
+func sumHistogramCount(h metricdata.Histogram[float64]) uint64 {
+	var n uint64
+	for _, dp := range h.DataPoints {
+		n += dp.Count
+	}
+	return n
+}
+
+func sumDataPoints[N int64 | float64](dps []metricdata.DataPoint[N]) N {
+	var n N
+	for _, dp := range dps {
+		n += dp.Value
+	}
+	return n
+}
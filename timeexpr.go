@@ -0,0 +1,157 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// epochMillisecondsThreshold distinguishes epoch milliseconds from epoch seconds.
+// 1e11 seconds is in the year 5138, 1e11 milliseconds is in 1973.
+const epochMillisecondsThreshold = 1e11
+
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTimeExpr parses the value of start_time and end_time.
+//
+// The following values are supported:
+//   - time.Time
+//   - time.Duration: the offset from now, for example -1h is one hour ago.
+//   - integer: Unix epoch in seconds or milliseconds.
+//   - string: RFC3339, `now`, `now-1h`, `-30m`, `today`, `yesterday`, epoch, or date and time without time zone in loc.
+//
+// In relative expressions, `d` (day) and `w` (week) units are available in addition to time.ParseDuration.
+func parseTimeExpr(v any, now time.Time, loc *time.Location) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case time.Duration:
+		return now.Add(v), nil
+	case int64:
+		return parseEpoch(v), nil
+	case int:
+		return parseEpoch(int64(v)), nil
+	case string:
+		return parseTimeString(strings.TrimSpace(v), now, loc)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func parseEpoch(v int64) time.Time {
+	if v >= epochMillisecondsThreshold || v <= -epochMillisecondsThreshold {
+		return time.UnixMilli(v)
+	}
+	return time.Unix(v, 0)
+}
+
+func parseTimeString(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	lower := strings.ToLower(s)
+	switch lower {
+	case "now":
+		return now, nil
+	case "today":
+		return startOfDay(now.In(loc)), nil
+	case "yesterday":
+		return startOfDay(now.In(loc)).AddDate(0, 0, -1), nil
+	}
+	if strings.HasPrefix(lower, "now") {
+		d, err := parseRelativeDuration(strings.ReplaceAll(lower[len("now"):], " ", ""))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+	if strings.HasPrefix(lower, "-") || strings.HasPrefix(lower, "+") {
+		if i, err := strconv.ParseInt(lower, 10, 64); err == nil {
+			return parseEpoch(i), nil
+		}
+		d, err := parseRelativeDuration(lower)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+	if i, err := strconv.ParseInt(lower, 10, 64); err == nil {
+		return parseEpoch(i), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time expression %q", s)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// parseRelativeDuration parses a signed duration such as `-1h30m`, `+2d` or `-1w`.
+func parseRelativeDuration(s string) (time.Duration, error) {
+	if len(s) < 2 || (s[0] != '-' && s[0] != '+') {
+		return 0, fmt.Errorf("invalid relative time %q", s)
+	}
+	sign := time.Duration(1)
+	if s[0] == '-' {
+		sign = -1
+	}
+	d, err := parseExtendedDuration(s[1:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid relative time %q: %w", s, err)
+	}
+	return sign * d, nil
+}
+
+// parseExtendedDuration is time.ParseDuration with `d` (24h) and `w` (7d) units.
+func parseExtendedDuration(s string) (time.Duration, error) {
+	var total time.Duration
+	for s != "" {
+		i := 0
+		for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+			i++
+		}
+		j := i
+		for j < len(s) && !isDigit(s[j]) && s[j] != '.' {
+			j++
+		}
+		if i == 0 || i == j {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		var unit time.Duration
+		switch s[i:j] {
+		case "d":
+			unit = 24 * time.Hour
+		case "w":
+			unit = 7 * 24 * time.Hour
+		}
+		if unit == 0 {
+			d, err := time.ParseDuration(s[:j])
+			if err != nil {
+				return 0, err
+			}
+			total += d
+		} else {
+			n, err := strconv.ParseFloat(s[:i], 64)
+			if err != nil {
+				return 0, err
+			}
+			total += time.Duration(n * float64(unit))
+		}
+		s = s[j:]
+	}
+	return total, nil
+}
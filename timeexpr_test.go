@@ -0,0 +1,67 @@
+package cloudwatchlogsinsightsdriver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeExpr(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		value    any
+		expected time.Time
+	}{
+		{now.Add(-time.Hour), now.Add(-time.Hour)},
+		{-30 * time.Minute, now.Add(-30 * time.Minute)},
+		{int64(1577836800), time.Unix(1577836800, 0)},
+		{int64(1577836800123), time.UnixMilli(1577836800123)},
+		{"1577836800", time.Unix(1577836800, 0)},
+		{"now", now},
+		{"now-1h", now.Add(-time.Hour)},
+		{"now - 2d", now.Add(-48 * time.Hour)},
+		{"-30m", now.Add(-30 * time.Minute)},
+		{"-1w", now.Add(-7 * 24 * time.Hour)},
+		{"-1h30m", now.Add(-90 * time.Minute)},
+		{"today", time.Date(2020, 1, 2, 0, 0, 0, 0, loc)},
+		{"yesterday", time.Date(2020, 1, 1, 0, 0, 0, 0, loc)},
+		{"2020-01-01T00:00:00+09:00", time.Date(2020, 1, 1, 0, 0, 0, 0, loc)},
+		{"2020-01-01", time.Date(2020, 1, 1, 0, 0, 0, 0, loc)},
+		{"2020-01-01 12:30:00", time.Date(2020, 1, 1, 12, 30, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		actual, err := parseTimeExpr(c.value, now, loc)
+		if err != nil {
+			t.Errorf("%v: %v", c.value, err)
+			continue
+		}
+		if !actual.Equal(c.expected) {
+			t.Errorf("%v: expected %s, got %s", c.value, c.expected, actual)
+		}
+	}
+}
+
+func TestParseTimeExpr__Error(t *testing.T) {
+	now := time.Now()
+	for _, v := range []any{"now-", "-1x", "tomorrow", "2020/01/01", 1.5} {
+		if _, err := parseTimeExpr(v, now, time.UTC); err == nil {
+			t.Errorf("%v: unexpected nil error", v)
+		}
+	}
+}
+
+func TestConfigParseDSN__DefaultRange(t *testing.T) {
+	cfg, err := ParseDSN("cloudwatch://?since=7d&timezone=Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DefaultRange != 7*24*time.Hour {
+		t.Errorf("unexpected default range: %s", cfg.DefaultRange)
+	}
+	if cfg.Location.String() != "Asia/Tokyo" {
+		t.Errorf("unexpected location: %s", cfg.Location)
+	}
+	if _, err := ParseDSN("cloudwatch://?since=7d&default_range=1h"); err == nil {
+		t.Error("unexpected nil error")
+	}
+}
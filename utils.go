@@ -1,5 +1,10 @@
 package cloudwatchlogsinsightsdriver
 
+import (
+	"context"
+	"sync"
+)
+
 func nullif[T comparable](v T) *T {
 	var empty T
 	if v == empty {
@@ -17,3 +22,39 @@ func coalesce[T any](vals ...*T) T {
 	var empty T
 	return empty
 }
+
+// runConcurrently calls fn for each 0 <= i < n with at most concurrency goroutines.
+// The context passed to fn is cancelled on the first error, and the first error is returned.
+func runConcurrently(ctx context.Context, n, concurrency int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if errs[i] = fn(ctx, i); errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}